@@ -1,29 +1,52 @@
 package checker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"geri.dev/pack-builder/config"
 	"geri.dev/pack-builder/providers"
 	"geri.dev/pack-builder/utils"
 	"geri.dev/pack-builder/web/sockets"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/hashicorp/go-version"
-	"io"
 	"net/http"
 	"os"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Checker struct {
 	Spigot            providers.SpigotProvider
 	Modrinth          providers.ModrinthProvider
+	Hangar            providers.HangarProvider
+	Maven             providers.MavenProvider
+	Ore               providers.OreProvider
+	CurseForge        providers.CurseForgeProvider
 	GitHub            providers.GitHubProvider
 	DirectDownload    providers.DirectDownloadProvider
+	PaperMC           providers.PaperMCProvider
+	Purpur            providers.PurpurProvider
 	PluginProviders   []providers.PluginProvider
 	ExternalProviders []providers.ExternalProvider
+	ServerProviders   []providers.ServerProvider
+	Compatibility     config.Compatibility
+	Pool              *DownloadPool
+	Quotas            config.Quotas
+
+	// The client files are downloaded with, which follows the outbound policy
+	Client *http.Client
+
+	// How many links are checked at the same time for each session
+	PreliminaryWorkers int
+
+	// How many times a download is retried after a temporary error
+	DownloadRetries int
 }
 
 type SocketTracker struct {
@@ -61,10 +84,57 @@ type Session struct {
 
 	Request Request `json:"request"`
 
-	Sockets []SocketTracker `json:"-"`
+	Sockets     []SocketTracker `json:"-"`
+	socketsLock sync.Mutex
 
 	OverallState OverallState         `json:"overall_state"`
 	Links        map[uuid.UUID]*State `json:"links"`
+	Server       *Download            `json:"server,omitempty"`
+	Java         *JavaRequirement     `json:"java,omitempty"`
+	Libraries    *Libraries           `json:"libraries,omitempty"`
+
+	// The size of the overrides copied from an imported pack, which count towards the quota
+	OverridesSize int64 `json:"overrides_size,omitempty"`
+
+	// The overrides copied from an imported pack that only belong on the server
+	ServerOverrides map[string]bool `json:"server_overrides,omitempty"`
+
+	// The stage that is currently running, so it can be cancelled
+	stage     *stage
+	stageLock sync.Mutex
+
+	// The paths of the files downloaded for the session, so none of them are overwritten
+	fileNames     map[string]bool
+	replaceable   map[string]bool
+	partLocks     map[string]*sync.Mutex
+	fileNamesLock sync.Mutex
+
+	// How much has been downloaded for the session, so it stays within its quota
+	usage     int64
+	usageLock sync.Mutex
+}
+
+// stage represents a stage of a session that is currently running
+type stage struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ErrStageRunning is returned when a stage is started while another one is still running
+var ErrStageRunning = fmt.Errorf("another stage is already running for the session")
+
+// Libraries represents the Maven libraries downloaded for the plugins
+type Libraries struct {
+	Artifacts []providers.MavenArtifact `json:"artifacts"`
+	Errors    []string                  `json:"errors,omitempty"`
+}
+
+// JavaRequirement represents the Java version the package needs to run
+// and the one the requested game version normally runs on
+type JavaRequirement struct {
+	Required int    `json:"required"`
+	Expected int    `json:"expected"`
+	Warning  string `json:"warning,omitempty"`
 }
 
 // OverallState represents the overall state of a session
@@ -93,17 +163,84 @@ func (s *Session) Initialize() {
 		}
 	}
 
-	// Ensure the working folders exist
+	s.setDirectories()
+	s.OverallState = OverallState{Initialized: true}
+}
+
+// Restore prepares a session that was loaded from disk, so it can be used again
+func (s *Session) Restore() {
+	s.Sockets = make([]SocketTracker, 0)
+	s.setDirectories()
+	s.resetDownloads(true)
+}
+
+// setDirectories sets the working folders of the session and ensures they exist
+func (s *Session) setDirectories() {
 	baseDirectory, _ := os.Getwd()
 	s.WorkingDirectory = path.Join(baseDirectory, s.Id.String())
 	s.DownloadsDirectory = path.Join(s.WorkingDirectory, "downloads")
 	_ = os.MkdirAll(s.DownloadsDirectory, 0660)
+}
 
-	s.OverallState = OverallState{Initialized: true}
+// getTargetDirectory returns the folder inside the package
+// that the files for a specific mode are downloaded to
+func (s *Session) getTargetDirectory(mode modeType) string {
+	if mode == Datapacks {
+		return path.Join(s.DownloadsDirectory, "world", "datapacks")
+	}
+	return path.Join(s.DownloadsDirectory, string(mode))
+}
+
+// StartStage marks a new stage as running and returns the context it should run with,
+// which is cancelled by CancelStage. Only one stage can run at a time
+func (s *Session) StartStage() (ctx context.Context, err error) {
+	s.stageLock.Lock()
+	defer s.stageLock.Unlock()
+
+	if s.stage != nil {
+		err = ErrStageRunning
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stage = &stage{cancel: cancel, done: make(chan struct{})}
+	return
+}
+
+// FinishStage marks the running stage as finished
+func (s *Session) FinishStage() {
+	s.stageLock.Lock()
+	defer s.stageLock.Unlock()
+
+	if s.stage != nil {
+		s.stage.cancel()
+		close(s.stage.done)
+		s.stage = nil
+	}
+}
+
+// CancelStage cancels the running stage and waits for it to stop
+// It returns false if there was no stage running
+func (s *Session) CancelStage() bool {
+	s.stageLock.Lock()
+	current := s.stage
+	s.stageLock.Unlock()
+
+	if current == nil {
+		return false
+	}
+
+	current.cancel()
+	<-current.done
+	return true
 }
 
 // Delete cleans up a session
 func (s *Session) Delete() {
+
+	// Ensure nothing is writing to the folder anymore
+	s.CancelStage()
+
 	if err := os.RemoveAll(s.WorkingDirectory); err != nil {
 		fmt.Printf("Unable to clean up session folder %s: %s\n", s.Id, err)
 	}
@@ -115,11 +252,41 @@ func (s *Session) Delete() {
 	s.OverallState.Deleted = true
 }
 
+// AddSocket starts tracking a websocket, so it receives everything broadcast to the session
+func (s *Session) AddSocket(ws *websocket.Conn) {
+	s.socketsLock.Lock()
+	defer s.socketsLock.Unlock()
+
+	s.Sockets = append(s.Sockets, SocketTracker{
+		Socket: ws,
+		Lock:   &sync.Mutex{},
+	})
+}
+
+// getSockets returns the sockets that are currently tracked, which
+// is safe to use while other sockets are added or removed
+func (s *Session) getSockets() []SocketTracker {
+	s.socketsLock.Lock()
+	defer s.socketsLock.Unlock()
+
+	return append([]SocketTracker{}, s.Sockets...)
+}
+
 // CloseSocket closes a specific websocket and stops tracking it
 func (s *Session) CloseSocket(ws *websocket.Conn) {
+	s.socketsLock.Lock()
+	defer s.socketsLock.Unlock()
+
 	for i, tracker := range s.Sockets {
 		if tracker.Socket == ws {
-			_ = tracker.Socket.Close()
+
+			// Wait for anything that is still being sent to it
+			if tracker.Socket != nil {
+				tracker.Lock.Lock()
+				_ = tracker.Socket.Close()
+				tracker.Lock.Unlock()
+			}
+
 			if len(s.Sockets) > 1 {
 				s.Sockets = append(s.Sockets[:i], s.Sockets[i+1:]...)
 			} else {
@@ -132,7 +299,7 @@ func (s *Session) CloseSocket(ws *websocket.Conn) {
 
 // CloseSockets calls CloseSocket on all sockets
 func (s *Session) CloseSockets() {
-	for _, tracker := range s.Sockets {
+	for _, tracker := range s.getSockets() {
 		s.CloseSocket(tracker.Socket)
 	}
 }
@@ -152,7 +319,8 @@ func (s *Session) BroadcastToSockets(message sockets.Message, rawData interface{
 	}
 
 	// Send the message to each socket
-	for _, tracker := range s.Sockets {
+	// Several stages can broadcast at once, so each socket is only written to by one of them at a time
+	for _, tracker := range s.getSockets() {
 
 		// If the socket is in an errored state, we'll clean it up
 		if tracker.Socket == nil {
@@ -177,6 +345,9 @@ type Request struct {
 	Platform        platformType      `json:"platform"`
 	PlatformVersion string            `json:"platform_version"`
 	GameVersion     string            `json:"game_version"`
+	Name            string            `json:"name,omitempty"`
+	ServerJar       bool              `json:"server_jar"`
+	Libraries       bool              `json:"libraries"`
 	Links           map[string]string `json:"links"`
 }
 
@@ -206,9 +377,37 @@ func (request *Request) Bind(r *http.Request) error {
 		return fmt.Errorf("invalid game version: %s", request.GameVersion)
 	}
 
+	// Ensure we are able to download the server JAR
+	if request.ServerJar {
+		if provider, _ := request.Platform.getServerJarSource(); provider == "" {
+			return fmt.Errorf("server JAR downloads are not supported for %s", request.Platform)
+		}
+
+		// For plugin platforms, the platform version is the build
+		if build := request.getServerBuild(); request.Mode == Plugins && build != "" {
+			if _, err := strconv.Atoi(build); err != nil {
+				return fmt.Errorf("invalid server build: %s", build)
+			}
+		}
+	}
+
+	// Only Bukkit-based platforms load libraries from the plugin.yml
+	if request.Libraries && !request.Platform.supportsLibraries() {
+		return fmt.Errorf("library downloads are not supported for %s", request.Platform)
+	}
+
 	return nil
 }
 
+// getServerBuild returns the requested build of the server
+// software or an empty string if the latest one should be used
+func (request *Request) getServerBuild() string {
+	if request.PlatformVersion == "latest" {
+		return ""
+	}
+	return request.PlatformVersion
+}
+
 // State represents the state of a single link
 type State struct {
 	Id             uuid.UUID       `json:"id"`
@@ -228,21 +427,43 @@ type Preliminary struct {
 	PluginInfo     *providers.PluginInfo        `json:"plugin_info"`
 	Links          map[string]bool              `json:"links"`
 	Certain        bool                         `json:"certain"`
+	Mode           modeType                     `json:"mode,omitempty"`
+	Version        *providers.Version           `json:"version,omitempty"`
 }
 
 // Download represents the state of a specific link
 // in the download stage
 type Download struct {
-	Status  status `json:"status"`
-	Message string `json:"message"`
-	Path    string `json:"path"`
-	Size    int64  `json:"size"`
+	Status      status            `json:"status"`
+	Error       sockets.ErrorType `json:"error,omitempty"`
+	Message     string            `json:"message"`
+	URL         string            `json:"url"`
+	Path        string            `json:"path"`
+	FileName    string            `json:"file_name"`
+	Size        int64             `json:"size"`
+	JavaVersion int               `json:"java_version,omitempty"`
 }
 
 // PostProcessing represents the state of a specific link
 // after the download stage
 type PostProcessing struct {
 	Dependencies []Dependency `json:"dependencies,omitempty"`
+	Suggestions  []Dependency `json:"suggestions,omitempty"`
+	Warnings     []Issue      `json:"warnings,omitempty"`
+	Conflicts    []Conflict   `json:"conflicts,omitempty"`
+}
+
+// Issue represents a non-fatal problem found with a link
+type Issue struct {
+	Type    sockets.ErrorType `json:"type"`
+	Message string            `json:"message"`
+}
+
+// Conflict represents another link that can't be used together with a link
+type Conflict struct {
+	Type    sockets.ErrorType `json:"type"`
+	Link    uuid.UUID         `json:"link"`
+	Message string            `json:"message"`
 }
 
 // Dependency represents the status of a single found dependency
@@ -252,6 +473,15 @@ type Dependency struct {
 	// If the dependency is one of the other plugins
 	OtherPlugin bool `json:"other_plugin"`
 
+	// If the dependency is bundled inside one of the other mods
+	Bundled bool `json:"bundled"`
+
+	// The names of the JARs that require it
+	Parents []string `json:"parents,omitempty"`
+
+	// The names leading from the link to the dependency, if it could not be resolved
+	Chain []string `json:"chain,omitempty"`
+
 	// The status of finding it online
 	Search *Preliminary `json:"search"`
 
@@ -264,13 +494,55 @@ type modeType string
 const (
 	Plugins modeType = "plugins"
 	Mods    modeType = "mods"
+	Hybrid  modeType = "hybrid"
+
+	// Data packs and resource packs are only used for links,
+	// since they are loaded by the game rather than the platform
+	Datapacks     modeType = "datapacks"
+	ResourcePacks modeType = "resourcepacks"
 )
 
+// isPack returns true if the mode is for data packs or resource packs
+func (mt modeType) isPack() bool {
+	return mt == Datapacks || mt == ResourcePacks
+}
+
+// getFileExtension returns the extension of the files downloaded for the mode
+func (mt modeType) getFileExtension() string {
+	if mt.isPack() {
+		return ".zip"
+	}
+	return ".jar"
+}
+
+// getPackageName returns the display name of the package for the mode
+func (mt modeType) getPackageName() string {
+	switch mt {
+	case Mods:
+		return "Mod Pack"
+	case Hybrid:
+		return "Hybrid Pack"
+	}
+	return "Plugin Pack"
+}
+
 // Represents the target platform
 type platformType string
 
 const (
-	Spigot   platformType = "spigot"
+	Spigot platformType = "spigot"
+	Paper  platformType = "paper"
+	Purpur platformType = "purpur"
+	Folia  platformType = "folia"
+	Sponge platformType = "sponge"
+
+	Velocity   platformType = "velocity"
+	BungeeCord platformType = "bungeecord"
+	Waterfall  platformType = "waterfall"
+
+	Mohist   platformType = "mohist"
+	Arclight platformType = "arclight"
+
 	Fabric   platformType = "fabric"
 	Quilt    platformType = "quilt"
 	Forge    platformType = "forge"
@@ -282,13 +554,14 @@ type platformInfo struct {
 	Name             string   `json:"name"`
 	PlatformVersions []string `json:"platform_versions"`
 	GameVersions     []string `json:"game_versions"`
+	ServerJar        bool     `json:"server_jar"`
 }
 
 // isValid returns true if the value is in the enum
 // Curse you Go!
 func (pt platformType) isValid() bool {
 	switch pt {
-	case Spigot, Fabric, Quilt, Forge, NeoForge:
+	case Spigot, Paper, Purpur, Folia, Sponge, Velocity, BungeeCord, Waterfall, Mohist, Arclight, Fabric, Quilt, Forge, NeoForge:
 		return true
 	default:
 		return false
@@ -298,14 +571,115 @@ func (pt platformType) isValid() bool {
 // getMode returns the mode for the platform
 func (pt platformType) getMode() modeType {
 	switch pt {
-	case Spigot:
+	case Spigot, Paper, Purpur, Folia, Sponge, Velocity, BungeeCord, Waterfall:
 		return Plugins
 	case Fabric, Quilt, Forge, NeoForge:
 		return Mods
+	case Mohist, Arclight:
+		return Hybrid
 	}
 	panic("invalid platform type: " + pt)
 }
 
+// getModes returns the modes that links can be loaded as
+// on the platform, in the order we prefer them
+func (pt platformType) getModes() []modeType {
+	if mode := pt.getMode(); mode != Hybrid {
+		return []modeType{mode}
+	}
+	return []modeType{Mods, Plugins}
+}
+
+// matchLoaders returns the mode that a build made for the given loaders would be
+// loaded as on the requested platform, along with its rank, where 0 is a native build
+// If a mode is passed, only that one is checked
+// Some providers, like Spigot do not specify the loaders, so we will assume those are plugins
+func (c *Checker) matchLoaders(request *Request, loaders []string, only modeType) (modeType, int, bool) {
+	for _, mode := range request.Platform.getModes() {
+		if only != "" && mode != only {
+			continue
+		}
+
+		if loaders == nil {
+			if mode == Plugins {
+				return mode, 0, true
+			}
+			continue
+		}
+
+		// The loaders are in the order they are preferred, so the first match is the best
+		supportedLoaders := c.Compatibility.GetLoaders(string(request.Platform), string(mode), request.GameVersion)
+		for rank, supportedLoader := range supportedLoaders {
+			for _, loader := range loaders {
+				if strings.ToLower(loader) == supportedLoader {
+					return mode, rank, true
+				}
+			}
+		}
+	}
+
+	// Data packs and resource packs work the same way on every platform
+	for _, loader := range loaders {
+		switch {
+		case loader == providers.DatapackLoader && (only == "" || only == Datapacks):
+			return Datapacks, 0, true
+		case loader == providers.ResourcePackLoader && (only == "" || only == ResourcePacks):
+			return ResourcePacks, 0, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// isProxy returns true if the platform is a proxy
+// that is versioned separately from the game
+func (pt platformType) isProxy() bool {
+	switch pt {
+	case Velocity, BungeeCord, Waterfall:
+		return true
+	}
+	return false
+}
+
+// supportsLibraries returns true if the platform downloads
+// the libraries plugins declare in their plugin.yml
+func (pt platformType) supportsLibraries() bool {
+	switch pt {
+	case Spigot, Paper, Purpur, Folia, Mohist, Arclight:
+		return true
+	}
+	return false
+}
+
+// getPluginParser returns the function that can parse
+// the plugin descriptor file of plugins for the platform
+func (pt platformType) getPluginParser() func(string) (utils.PluginConfig, error) {
+	switch pt {
+	case Velocity:
+		return utils.ParseVelocityPluginJson
+	case BungeeCord, Waterfall:
+		return utils.ParseBungeeYaml
+	case Sponge:
+		return utils.ParseSpongePluginsJson
+	case Paper, Purpur, Folia:
+		return utils.ParsePaperPluginYaml
+	}
+	return utils.ParsePluginYaml
+}
+
+// getServerJarSource returns the name of the server provider and the
+// project that the platform's server JAR can be downloaded from
+// If the platform does not support it, both will be empty
+func (pt platformType) getServerJarSource() (provider string, project string) {
+	switch pt {
+	case Paper, Folia, Velocity, Waterfall:
+		return "papermc", string(pt)
+	case Purpur:
+		return "purpur", string(pt)
+	}
+	return "", ""
+}
+
 // Represents the status of an operation
 type status string
 
@@ -317,26 +691,116 @@ const (
 
 // PreliminaryChecks goes through each link and using all the available providers,
 // attempts to parse it and retrieve its basic information and a list of possible downloads
-func (c *Checker) PreliminaryChecks(session *Session) {
+func (c *Checker) PreliminaryChecks(ctx context.Context, session *Session) {
+
+	// The stage is running again, so neither it nor the ones after it are done until it finishes
+	session.OverallState.Preliminary = false
+	session.OverallState.Download = false
+	session.OverallState.PostProcessing = false
+	session.OverallState.Package = false
+
+	workers := c.PreliminaryWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Check the links at the same time, but only a few at once
+	var wg sync.WaitGroup
+	var progressLock sync.Mutex
+	limiter := make(chan struct{}, workers)
+	started := time.Now()
+	done := 0
+
+	for _, state := range session.Links {
 
-	switch session.Request.Mode {
+		// Do not start any new checks once we have been cancelled
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		limiter <- struct{}{}
+
+		go func(state *State) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+
+			result := c.getPluginInformation(ctx, session, getPluginInformationOptions{checkWithLink: true, link: state.Link})
+
+			// The result of an interrupted check is not reliable,
+			// so we will keep the link as it was
+			if ctx.Err() != nil {
+				return
+			}
 
-	case Plugins:
-		for _, state := range session.Links {
-			result := c.getPluginInformation(session, getPluginInformationOptions{checkWithLink: true, link: state.Link})
 			state.Preliminary = &result
 			_ = session.BroadcastToSockets(sockets.PreliminaryStep, state)
-		}
-		break
 
-	case Mods:
-		break
+			progressLock.Lock()
+			done++
+			progress := newProgress(done, len(session.Links), started)
+			progressLock.Unlock()
+
+			_ = session.BroadcastToSockets(sockets.PreliminaryProgress, progress)
+		}(state)
+	}
+
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
 	}
 
 	session.OverallState.Preliminary = true
 	return
 }
 
+// providerLookup represents the result of looking up a project with a single provider
+type providerLookup struct {
+	info providers.PluginInfo
+	err  error
+}
+
+// lookupProviders looks up a project with each plugin provider at the same time
+// and returns the result of the first provider in priority order that was able to find it
+// The failed attempts are stored in the result under the passed key
+func (c *Checker) lookupProviders(ctx context.Context, result *Preliminary, key string, lookup func(providers.PluginProvider) (providers.PluginInfo, error)) *providers.PluginInfo {
+	lookups := make([]providerLookup, len(c.PluginProviders))
+
+	var wg sync.WaitGroup
+	for i, provider := range c.PluginProviders {
+		wg.Add(1)
+		go func(i int, provider providers.PluginProvider) {
+			defer wg.Done()
+			lookups[i].info, lookups[i].err = lookup(provider)
+		}(i, provider)
+	}
+	wg.Wait()
+
+	var info *providers.PluginInfo
+	for i, provider := range c.PluginProviders {
+		if lookups[i].err != nil { // Store the failed attempt
+			result.FailedAttempts[provider.GetPluginProviderName()][key] = lookups[i].err.Error()
+			continue
+		}
+
+		if info == nil {
+			info = &lookups[i].info
+		}
+	}
+
+	return info
+}
+
+// versionCandidate represents a version from a provider that would work on
+// the requested platform, along with how well its loaders match it
+type versionCandidate struct {
+	version providers.Version
+	mode    modeType
+	rank    int
+}
+
 // getPluginInformationOptions allows us to download plugins
 // based on just a link or just a name, or both
 type getPluginInformationOptions struct {
@@ -350,12 +814,15 @@ type getPluginInformationOptions struct {
 
 	// Whether the name or link is already an external one
 	external bool
+
+	// Only accept builds that are loaded as this mode, if set
+	mode modeType
 }
 
 // getPluginInformation goes through all of our plugin providers
 // and attempts to get the plugin information and the necessary version
 // for a specific session and a link or project name
-func (c *Checker) getPluginInformation(session *Session, options getPluginInformationOptions) (result Preliminary) {
+func (c *Checker) getPluginInformation(ctx context.Context, session *Session, options getPluginInformationOptions) (result Preliminary) {
 
 	// New opportunities n all that
 	result.Status = Success
@@ -374,73 +841,115 @@ func (c *Checker) getPluginInformation(session *Session, options getPluginInform
 
 	// First try it as a link
 	if options.checkWithLink {
-		for _, provider := range c.PluginProviders {
-			if i, err := provider.GetPluginInfoFromLink(options.link); err != nil { // Store the failed attempt
-				result.FailedAttempts[provider.GetPluginProviderName()]["link"] = err.Error()
-				continue
-			} else {
-				info = &i
-			}
-		}
+		info = c.lookupProviders(ctx, &result, "link", func(provider providers.PluginProvider) (providers.PluginInfo, error) {
+			return provider.GetPluginInfoFromLink(ctx, options.link)
+		})
 	}
 
 	// If none of them were able to parse it as a link, we
 	// will try to look it up as a project name
 	if info == nil && options.checkWithName {
-		for _, provider := range c.PluginProviders {
-			if i, err := provider.GetPluginInfoFromProjectName(options.name); err != nil { // Store the failed attempt
-				result.FailedAttempts[provider.GetPluginProviderName()]["name"] = err.Error()
+		info = c.lookupProviders(ctx, &result, "name", func(provider providers.PluginProvider) (providers.PluginInfo, error) {
+			return provider.GetPluginInfoFromProjectName(ctx, options.name)
+		})
+	}
+
+	// If it's not a project link either, it might be a direct
+	// link to a JAR, so we will give the external providers a go
+	var blocked error
+	if info == nil && options.checkWithLink && !options.external {
+		for _, provider := range c.ExternalProviders {
+			rawLinks, err := provider.GetJARDownloadLinksFromLink(ctx, options.link)
+			if err != nil || len(rawLinks) == 0 {
+				if err != nil {
+					result.FailedAttempts[provider.GetExternalProviderName()]["link"] = err.Error()
+					if utils.IsBlocked(err) {
+						blocked = err
+					}
+				}
 				continue
-			} else {
-				info = &i
 			}
+
+			links := make(map[string]bool)
+			for _, link := range rawLinks {
+				links[link] = true
+			}
+
+			// We don't know anything about the project, so we will name it after the file
+			result.PluginInfo = &providers.PluginInfo{
+				Type: providers.Direct,
+				Id:   options.link,
+				Link: options.link,
+				Name: strings.TrimSuffix(path.Base(rawLinks[0]), ".jar"),
+			}
+
+			result.Mode = options.mode
+			if result.Mode == "" {
+				result.Mode = session.Request.Platform.getModes()[0]
+			}
+
+			result.Certain = false
+			result.Links = links
+			return
 		}
 	}
 
 	if info == nil {
 		result.Status = Error
 		result.Message = "none of the providers were able to handle the link"
+		if blocked != nil {
+			result.Error = sockets.BlockedLink
+			result.Message = blocked.Error()
+		}
 		return
 	}
 
 	result.PluginInfo = info
 
-	// Try each file we got from the provider until we find
-	// one that matches our version
+	// Find each file we got from the provider that matches our version
+	candidates := make([]versionCandidate, 0)
 	for _, version := range info.Versions {
 
 		// Check if one of the supported platform match
 		// with what we are looking for
-		// Some providers, like Spigot do not specify this, so
-		// we will just skip it at this stage
-		if version.Platforms != nil {
-			loaderFound := false
-			for _, loader := range version.Platforms {
-				if strings.ToLower(loader) == string(session.Request.Platform) {
-					loaderFound = true
-					break
-				}
-			}
-			if !loaderFound {
-				continue
-			}
+		mode, rank, loaderFound := c.matchLoaders(&session.Request, version.Platforms, options.mode)
+		if !loaderFound {
+			continue
 		}
 
 		// Check if the supported versions include what we are looking for
 		// Some older plugins will work just fine but do not have versions specified,
 		// so we will just skip it
 		// Todo (notgeri): Add a warning if there aren't any others, so the user can decide if they want to include it
-		if version.GameVersions != nil && len(version.GameVersions) > 0 {
+		// Proxies do not share their versions with the game, so the versions
+		// some providers list for proxy plugins can't be matched against it
+		if version.GameVersions != nil && len(version.GameVersions) > 0 && !session.Request.Platform.isProxy() {
 			if isTested, err := version.IsTestedVersion(session.Request.GameVersion); err != nil || !isTested {
 				continue
 			}
 		}
 
+		candidates = append(candidates, versionCandidate{version: version, mode: mode, rank: rank})
+	}
+
+	// Prefer the files made for the platform over the ones that are only
+	// compatible with it, while keeping the order of the provider otherwise
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].rank < candidates[j].rank
+	})
+
+	// Try each of them until we find one that we can download
+	for _, candidate := range candidates {
+		version, mode := candidate.version, candidate.mode
+
 		// Let's try to get a working direct download link
 		// If it's not a regular a direct .jar link,
 		// we will try the other providers, such as GitHub
 		if !version.IsExternal {
+			selected := version
 			result.Certain = true
+			result.Mode = mode
+			result.Version = &selected
 			result.Links = map[string]bool{version.URL: true}
 			return
 		}
@@ -460,10 +969,16 @@ func (c *Checker) getPluginInformation(session *Session, options getPluginInform
 
 		// Sometimes developers link people from Spigot to Modrinth or similar,
 		// so first, try each primary provider
-		options.external = true
-		primaryResult := c.getPluginInformation(session, options)
+		primaryResult := c.getPluginInformation(ctx, session, getPluginInformationOptions{
+			checkWithLink: true,
+			link:          version.URL,
+			external:      true,
+			mode:          options.mode,
+		})
 		if primaryResult.Status == Success {
 			result.Status = Success
+			result.Mode = primaryResult.Mode
+			result.Version = primaryResult.Version
 			result.PluginInfo = primaryResult.PluginInfo
 			result.Links = primaryResult.Links
 			return
@@ -475,9 +990,12 @@ func (c *Checker) getPluginInformation(session *Session, options getPluginInform
 
 		// If that does not work, we will try each external provider
 		for _, provider := range c.ExternalProviders {
-			rawLinks, err := provider.GetJARDownloadLinksFromLink(version.URL)
+			rawLinks, err := provider.GetJARDownloadLinksFromLink(ctx, version.URL)
 			if err != nil {
 				result.FailedAttempts[provider.GetExternalProviderName()]["name"] = err.Error()
+				if utils.IsBlocked(err) {
+					blocked = err
+				}
 				continue
 			}
 
@@ -488,35 +1006,53 @@ func (c *Checker) getPluginInformation(session *Session, options getPluginInform
 
 			result.Status = Success
 			result.Certain = false
+			result.Mode = mode
 			result.Links = links
 			return
 		}
 	}
 
+	// If the only versions we found link somewhere we are not allowed to go, let them know
 	result.Status = Error
 	result.Error = sockets.NoSuitableVersion
+	if blocked != nil {
+		result.Error = sockets.BlockedLink
+		result.Message = blocked.Error()
+	}
 	return
 }
 
 // DownloadFiles attempts to download the fetched release
 // for all links in a session
-func (c *Checker) DownloadFiles(session *Session) {
+func (c *Checker) DownloadFiles(ctx context.Context, session *Session) {
 
-	// Batch the download jobs to be done concurrently
-	batchSize := 5
-	batch := 0
-	var wg sync.WaitGroup
+	// The stage is running again, so neither it nor the ones after it are done until it finishes
+	session.OverallState.Download = false
+	session.OverallState.PostProcessing = false
+	session.OverallState.Package = false
 
-	for linkId, state := range session.Links {
+	// Keep the sockets updated on how much of each file we have so far
+	files := len(session.Links)
+	if session.Request.ServerJar {
+		files++
+	}
+	tracker := newDownloadTracker(session, files)
 
-		wg.Add(1)
-		go func(linkId uuid.UUID, state *State) {
+	// Everything is downloaded again, so the previous names and sizes do not count anymore
+	session.resetDownloads(false)
+
+	// Each link is downloaded on the pool, along with the server JAR
+	jobs := make([]func(), 0)
+	for linkId, state := range session.Links {
+		linkId, state := linkId, state
+		jobs = append(jobs, func() {
 			defer func() {
+				tracker.finish()
 				_ = session.BroadcastToSockets(sockets.ProcessStep, state)
-				wg.Done()
 			}()
 
-			if state.Preliminary.Status != Success {
+			// The preliminary checks may have been cancelled before reaching this link
+			if state.Preliminary == nil || state.Preliminary.Status != Success {
 				session.Links[linkId].Download = &Download{
 					Status:  Error,
 					Message: "no download link from previous stage",
@@ -530,8 +1066,40 @@ func (c *Checker) DownloadFiles(session *Session) {
 					continue
 				}
 
-				// Download and verify the JAR // Todo (notgeri): we should use the name that is provided
-				result := c.downloadAndVerifyJar(availableLink, session.DownloadsDirectory, state.Preliminary.PluginInfo.Name+".jar")
+				// Not every server sends the size or the name of the file,
+				// so we will fall back to what the provider told us
+				mode := state.Preliminary.Mode
+				name := fileName{Fallback: state.Preliminary.PluginInfo.Name, Extension: mode.getFileExtension()}
+				var expectedSize int64
+				if version := state.Preliminary.Version; version != nil && version.URL == availableLink {
+					expectedSize = version.Size
+					name.Provided = version.FileName
+				}
+
+				progress := func(received, total int64) {
+					if total <= 0 {
+						total = expectedSize
+					}
+					tracker.update(linkId, received, total)
+				}
+
+				// Download and verify the JAR
+				result := c.downloadAndVerifyJar(ctx, session, availableLink, session.getTargetDirectory(mode), name, progress)
+
+				// Packs are not JARs, so we will have to make sure they have a valid pack.mcmeta instead
+				if result.Status == Success && mode.isPack() {
+					if _, err := utils.ParsePackMcmeta(result.Path); err != nil {
+						session.discardDownload(&result)
+						result.Status = Error
+						result.Message = fmt.Sprintf("not a valid pack: %s", err)
+					}
+				}
+
+				// An interrupted download has not failed, it just has not finished
+				if ctx.Err() != nil {
+					session.Links[linkId].Download = nil
+					return
+				}
 
 				// If the download was successful, we have nothing else to do here
 				session.Links[linkId].Download = &result
@@ -540,67 +1108,168 @@ func (c *Checker) DownloadFiles(session *Session) {
 				}
 			}
 
-			session.Links[linkId].Download = &Download{
+			// Keep the reason, so it's clear if the links were not allowed
+			failed := &Download{
 				Status:  Error,
 				Message: "none of the downloads worked",
 			}
+			if last := session.Links[linkId].Download; last != nil {
+				failed.Error = last.Error
+			}
+			session.Links[linkId].Download = failed
+		})
+	}
 
-		}(linkId, state)
+	jobs = append(jobs, func() {
+		c.downloadServerJar(ctx, session, tracker)
+	})
 
-		// Wait for the batch to complete
-		batch++
-		if batch >= batchSize {
-			wg.Wait()
-			batch = 0
-		}
+	c.Pool.Run(ctx, session.Id, jobs...)
+	if ctx.Err() != nil {
+		return
 	}
 
-	// Wait for the last batch if it wasn't full
-	wg.Wait()
 	session.OverallState.Download = true
 	return
 }
 
-// downloadAndVerifyJar downloads to a specific path and verifies the link as a JAR
-// This is done just with a simple size check and by checking the magic bytes
-func (c *Checker) downloadAndVerifyJar(link, folderPath, fileName string) (result Download) {
+// downloadServerJar downloads the server software for the
+// session's platform into the root of the package if it was requested
+func (c *Checker) downloadServerJar(ctx context.Context, session *Session, tracker *downloadTracker) {
+	if !session.Request.ServerJar {
+		return
+	}
 
-	result.Status = Success
-	fullPath := path.Join(path.Join(folderPath, fileName))
+	defer func() {
+		tracker.finish()
+		_ = session.BroadcastToSockets(sockets.ServerStep, session.Server)
+	}()
+
+	// Find the provider for the platform
+	providerName, project := session.Request.Platform.getServerJarSource()
+	var provider providers.ServerProvider
+	for _, serverProvider := range c.ServerProviders {
+		if serverProvider.GetServerProviderName() == providerName {
+			provider = serverProvider
+			break
+		}
+	}
 
-	// Download the file
-	resp, err := http.Get(link)
-	if err != nil {
-		result.Status = Error
-		result.Message = fmt.Sprintf("error downloading: %s", err)
+	if provider == nil {
+		session.Server = &Download{
+			Status:  Error,
+			Message: fmt.Sprintf("no server provider found for %s", session.Request.Platform),
+		}
 		return
 	}
 
-	defer resp.Body.Close()
+	// Proxies do not share their versions with the game,
+	// so for those we will just use the latest build
+	gameVersion, build := session.Request.GameVersion, session.Request.getServerBuild()
+	if session.Request.Platform.isProxy() {
+		gameVersion, build = "", ""
+	}
 
-	// Create the file
-	out, err := os.Create(fullPath)
+	// Look up the requested build
+	jar, err := provider.GetServerJar(ctx, project, gameVersion, build)
 	if err != nil {
-		result.Status = Error
-		result.Message = fmt.Sprintf("error creating file: %s", err)
+		session.Server = &Download{
+			Status:  Error,
+			Message: fmt.Sprintf("unable to find server JAR: %s", err),
+		}
 		return
 	}
 
-	defer out.Close()
+	// Download and verify the JAR
+	name := fileName{Provided: jar.FileName, Fallback: project, Extension: ".jar"}
+	result := c.downloadAndVerifyJar(ctx, session, jar.URL, session.DownloadsDirectory, name, func(received, total int64) {
+		tracker.update(uuid.Nil, received, total)
+	})
+	if result.Status == Success {
+		if err := utils.VerifyChecksum(result.Path, jar.Checksum.Algorithm, jar.Checksum.Hash); err != nil {
+			session.discardDownload(&result)
+			result.Status = Error
+			result.Message = err.Error()
+		}
+	}
 
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		result.Status = Error
-		result.Message = fmt.Sprintf("error writing to file: %s", err)
+	// An interrupted download has not failed, it just has not finished
+	if ctx.Err() != nil {
+		session.Server = nil
 		return
 	}
 
-	// Check file size
-	fi, err := os.Stat(fullPath)
-	if err != nil {
+	session.Server = &result
+}
+
+// downloadAndVerifyJar downloads to a specific path and verifies the link as a JAR
+// This is done just with a simple size check and by checking the magic bytes
+// If a progress callback is passed, it is called with the bytes received so far
+// and the size of the whole file, which is -1 if the server did not report it
+func (c *Checker) downloadAndVerifyJar(ctx context.Context, session *Session, link, folderPath string, name fileName, progress func(received, total int64)) (result Download) {
+
+	result.Status = Success
+	result.URL = link
+
+	// Ensure the folder exists
+	if err := os.MkdirAll(folderPath, 0755); err != nil {
 		result.Status = Error
-		result.Message = fmt.Sprintf("unable to check file: %s", err)
+		result.Message = fmt.Sprintf("error creating folder: %s", err)
+		return
+	}
+
+	// Download the file next to where it will end up, so an interrupted
+	// download can be continued, even after the backend was restarted
+	partPath := getPartPath(folderPath, link)
+	defer session.lockPartFile(partPath)()
+
+	quota := c.newDownloadQuota(session)
+	serverName, err := c.downloadFile(ctx, link, partPath, quota, progress)
+	if err != nil {
+
+		// Only keep what we have if we may still continue it later
+		if ctx.Err() == nil {
+			_ = os.Remove(partPath)
+			quota.release()
+		}
+
+		result.Status = Error
+		result.Message = fmt.Sprintf("error downloading: %s", err)
+		if utils.IsBlocked(err) {
+			result.Error = sockets.BlockedLink
+		} else if isQuotaExceeded(err) {
+			result.Error = sockets.QuotaExceeded
+		}
+		return
+	}
+
+	// Now that we know what the file is called, make sure it does not replace another one
+	fullPath := session.reserveFileName(folderPath, name.resolve(serverName))
+	result.FileName = path.Base(fullPath)
+
+	// A file that fails any of the checks is not kept, so it does not end up in the package
+	defer func() {
+		if result.Status != Success {
+			_ = os.Remove(fullPath)
+			session.releaseFileName(fullPath)
+			quota.release()
+			result.Path = ""
+			result.FileName = ""
+		}
+	}()
+
+	if err := os.Rename(partPath, fullPath); err != nil {
+		_ = os.Remove(partPath)
+		result.Status = Error
+		result.Message = fmt.Sprintf("error saving file: %s", err)
+		return
+	}
+
+	// Check file size
+	fi, err := os.Stat(fullPath)
+	if err != nil {
+		result.Status = Error
+		result.Message = fmt.Sprintf("unable to check file: %s", err)
 		return
 	}
 
@@ -636,149 +1305,794 @@ func (c *Checker) downloadAndVerifyJar(link, folderPath, fileName string) (resul
 		return
 	}
 
+	// Find out which Java version it needs, some JARs may not have any classes
+	if javaVersion, err := utils.GetRequiredJavaVersion(fullPath); err == nil {
+		result.JavaVersion = javaVersion
+	}
+
 	return
 }
 
 // PostProcessing handles any remaining steps, such as checking for
 // additional dependencies, cleaning up, and so on
-func (c *Checker) PostProcessing(session *Session) {
-	switch session.Request.Mode {
-	case Plugins: // For plugins, we will check the plugin.yml for any dependencies
-		c.checkPluginDependencies(session)
-		break
+func (c *Checker) PostProcessing(ctx context.Context, session *Session) {
+
+	// The stage is running again, so neither it nor the one after it are done until it finishes
+	session.OverallState.PostProcessing = false
+	session.OverallState.Package = false
+
+	descriptors := make(map[uuid.UUID]descriptor)
+	for _, mode := range session.Request.Platform.getModes() {
+		var parsed map[uuid.UUID]descriptor
+		switch mode {
+		case Plugins: // For plugins, we will check the plugin descriptor for any dependencies
+			parsed = c.checkDependencies(ctx, session, Plugins, parsePluginDescriptor)
+			break
+
+		case Mods: // For mods, we will check the mods.toml or fabric.mod.json for any dependencies
+			parsed = c.checkDependencies(ctx, session, Mods, parseModDescriptor)
+			break
+		}
+
+		for linkId, jar := range parsed {
+			descriptors[linkId] = jar
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	checkConflicts(session, descriptors)
+	if session.Request.Libraries {
+		c.downloadLibraries(ctx, session)
+		if ctx.Err() != nil {
+			return
+		}
 	}
 
+	checkJavaVersion(session)
+	checkPackFormats(session)
 	session.OverallState.PostProcessing = true
 }
 
-// CheckPluginDependencies goes through each downloaded
-// JAR file and checks if there are any missing hard
-// dependencies in the plugin.yml config file
-func (c *Checker) checkPluginDependencies(session *Session) {
+// descriptor represents the names a downloaded JAR provides,
+// the names of the JARs bundled inside it and the names
+// of the dependencies it requires
+type descriptor struct {
+	Names       []string
+	Bundled     []string
+	Depends     []string
+	SoftDepends []string
+	Breaks      []string
+	Conflicts   []string
+	Issues      []Issue
+}
+
+// parsePluginDescriptor parses a plugin's descriptor, such as plugin.yml
+func parsePluginDescriptor(session *Session, filePath string) (result descriptor, err error) {
+	plugin, err := session.Request.Platform.getPluginParser()(filePath)
+	if err != nil {
+		return
+	}
+
+	if plugin.Name == "" {
+		err = fmt.Errorf("plugin name was somehow empty")
+		return
+	}
+
+	// Folia plugins have to explicitly mark themselves as supported
+	if session.Request.Platform == Folia && !plugin.FoliaSupported {
+		result.Issues = append(result.Issues, Issue{
+			Type:    sockets.NotFoliaSupported,
+			Message: "the plugin is not marked as Folia supported",
+		})
+	}
+
+	// Bukkit refuses to load plugins made for a newer API than the server's
+	if plugin.ApiVersion != "" {
+		apiVersion, apiErr := version.NewVersion(plugin.ApiVersion)
+		gameVersion, gameErr := version.NewVersion(session.Request.GameVersion)
+		if apiErr == nil && gameErr == nil && apiVersion.GreaterThan(gameVersion) {
+			result.Issues = append(result.Issues, Issue{
+				Type:    sockets.NewerApiVersion,
+				Message: fmt.Sprintf("the plugin was made for %s, which is newer than %s", plugin.ApiVersion, session.Request.GameVersion),
+			})
+		}
+	}
+
+	result.Names = []string{plugin.Name}
+	result.Depends = plugin.Depends
+	result.SoftDepends = plugin.SoftDepends
+	return
+}
+
+// parseModDescriptor parses all the mods declared in a mod JAR
+func parseModDescriptor(_ *Session, filePath string) (result descriptor, err error) {
+	mods, err := utils.ParseModMetadata(filePath)
+	if err != nil {
+		return
+	}
+
+	for _, mod := range mods {
+		if mod.Id == "" {
+			continue
+		}
+
+		if mod.Bundled {
+			result.Bundled = append(result.Bundled, mod.Id)
+			continue
+		}
+
+		result.Names = append(result.Names, mod.Id)
+		result.Breaks = append(result.Breaks, mod.Breaks...)
+		result.Conflicts = append(result.Conflicts, mod.Conflicts...)
+	}
+
+	if len(result.Names) == 0 {
+		err = fmt.Errorf("no mods declared")
+		return
+	}
+
+	// A JAR can declare several mods that depend on each other,
+	// those are always there, so they are not dependencies of the JAR
+	for _, mod := range mods {
+		if mod.Id == "" || mod.Bundled {
+			continue
+		}
+
+		for _, dependency := range mod.Depends {
+			if indexOf(result.Names, strings.ToLower(dependency)) == -1 {
+				result.Depends = append(result.Depends, dependency)
+			}
+		}
+	}
+
+	return
+}
+
+// dependencyRequest represents a dependency required by one of the JARs,
+// along with the link it was reached from and the names leading to it
+type dependencyRequest struct {
+	linkId uuid.UUID
+	name   string
+	chain  []string
+
+	// If it was reached through another link, which
+	// resolves its own dependencies, so this is only for finding cycles
+	checkOnly bool
+}
+
+// indexOf returns the index of a name in a list, or -1 if it is not in it
+func indexOf(names []string, name string) int {
+	for i, other := range names {
+		if other == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkDependencies goes through each downloaded JAR file
+// of a specific mode and checks if there are any missing hard
+// dependencies in their descriptor, such as the plugin.yml
+// The dependencies we download are checked the same way, until there are no new ones
+// It returns the parsed descriptor of each link
+func (c *Checker) checkDependencies(ctx context.Context, session *Session, mode modeType, parse func(*Session, string) (descriptor, error)) (descriptors map[uuid.UUID]descriptor) {
 
-	// Go through each plugin JAR and check their name and dependencies
-	downloadedPlugins := make(map[uuid.UUID]string)
-	requiredDependencies := make(map[uuid.UUID][]string)
+	// Go through each JAR and check their names and dependencies
+	descriptors = make(map[uuid.UUID]descriptor)
+	linkNames := make(map[string]uuid.UUID)
+	providedNames := make(map[uuid.UUID][]string)
+	bundledNames := make(map[string]uuid.UUID)
+	queue := make([]dependencyRequest, 0)
 
 	for linkId, state := range session.Links {
 		result := state.Download
-		if result.Status != Success {
+		if result == nil || result.Status != Success || state.Preliminary.Mode != mode {
 			continue
 		}
 
-		plugin, err := utils.ParsePluginYaml(result.Path)
+		jar, err := parse(session, result.Path)
 		if err != nil {
-			fmt.Printf("Unable to parse plugin %s (%s): %s\n", linkId, result.Path, err)
+			fmt.Printf("Unable to parse %s %s (%s): %s\n", mode, linkId, result.Path, err)
 			continue
 		}
 
-		if plugin.Name == "" {
-			fmt.Printf("Plugin name was somehow empty %s\n", linkId)
-			continue
+		state.PostProcessing = &PostProcessing{Warnings: jar.Issues}
+		descriptors[linkId] = jar
+
+		// Ensure the names and all the dependencies are in lowercase
+		for _, name := range jar.Names {
+			linkNames[strings.ToLower(name)] = linkId
+			providedNames[linkId] = append(providedNames[linkId], strings.ToLower(name))
 		}
 
-		// Ensure the name and all the dependencies are in lowercase
-		downloadedPlugins[linkId] = strings.ToLower(plugin.Name)
-		if len(plugin.Depends) > 0 {
-			pluginDependencies := make([]string, 0)
-			for _, dependency := range plugin.Depends {
-				pluginDependencies = append(pluginDependencies, strings.ToLower(dependency))
+		for _, name := range jar.Bundled {
+			bundledNames[strings.ToLower(name)] = linkId
+		}
+	}
+
+	// The chains of dependencies start from the first name of each link
+	for linkId, jar := range descriptors {
+		for _, dependency := range jar.Depends {
+			queue = append(queue, dependencyRequest{
+				linkId: linkId,
+				name:   strings.ToLower(dependency),
+				chain:  []string{providedNames[linkId][0]},
+			})
+		}
+	}
+
+	// Warn about JARs that are already bundled inside another one
+	for linkId, names := range providedNames {
+		for _, name := range names {
+			bundlerId, found := bundledNames[name]
+			if !found || bundlerId == linkId {
+				continue
 			}
 
-			requiredDependencies[linkId] = pluginDependencies
+			state := session.Links[linkId]
+			state.PostProcessing.Warnings = append(state.PostProcessing.Warnings, Issue{
+				Type:    sockets.DuplicateBundled,
+				Message: fmt.Sprintf("%s is already bundled in %s", name, session.Links[bundlerId].Link),
+			})
 		}
 	}
 
-	// Go through each plugin's dependencies and check if there are any that are missing
-	downloadedDependencies := make(map[string]Dependency)
-	for parentId, pluginDependencies := range requiredDependencies {
-		dependencies := make([]Dependency, 0)
+	// Go through the dependencies and their dependencies until there are no new ones
+	resolved := make(map[string]*Dependency)
+	resolvedDepends := make(map[*Dependency][]string)
+	visited := make(map[string]bool)
+	checked := make(map[string]bool)
+	reportedCycles := make(map[string]bool)
+	linkDependencies := make(map[uuid.UUID][]string)
+
+	for len(queue) > 0 && ctx.Err() == nil {
+		request := queue[0]
+		queue = queue[1:]
+
+		// A JAR that requires one of the JARs that required it will never load
+		if index := indexOf(request.chain, request.name); index != -1 {
+			cycle := append(append([]string{}, request.chain[index:]...), request.name)
+			message := fmt.Sprintf("circular dependency: %s", strings.Join(cycle, " -> "))
+			if key := fmt.Sprintf("%s/%s", request.linkId, message); !reportedCycles[key] {
+				reportedCycles[key] = true
+
+				state := session.Links[request.linkId]
+				state.PostProcessing.Warnings = append(state.PostProcessing.Warnings, Issue{
+					Type:    sockets.DependencyCycle,
+					Message: message,
+				})
+			}
+			continue
+		}
+
+		// Each dependency only has to be followed once for each link
+		key := fmt.Sprintf("%s/%s", request.linkId, request.name)
+		if visited[key] || (request.checkOnly && checked[key]) {
+			continue
+		}
 
-		for _, dependencyName := range pluginDependencies {
-			dependency := Dependency{
-				Name: dependencyName,
+		if request.checkOnly {
+			checked[key] = true
+		} else {
+			visited[key] = true
+		}
+
+		chain := append(append([]string{}, request.chain...), request.name)
+
+		// Links resolve their own dependencies, we only have to follow them for cycles
+		if otherId, isLink := linkNames[request.name]; isLink {
+			if !request.checkOnly {
+				dependency := getDependency(resolved, request.name)
+				dependency.OtherPlugin = true
+				dependency.addParent(request.chain[len(request.chain)-1])
+				linkDependencies[request.linkId] = append(linkDependencies[request.linkId], request.name)
 			}
 
-			// See if it's one we already downloaded
-			downloadedDependency, found := downloadedDependencies[dependency.Name]
-			if found {
-				dependency.OtherPlugin = downloadedDependency.OtherPlugin
-				dependency.Search = downloadedDependency.Search
-				dependency.Download = downloadedDependency.Download
+			for _, name := range descriptors[otherId].Depends {
+				queue = append(queue, dependencyRequest{linkId: request.linkId, name: strings.ToLower(name), chain: chain, checkOnly: true})
 			}
+			continue
+		}
+
+		if request.checkOnly {
+			continue
+		}
 
-			// See if it's one of the other plugins
-			if !found {
-				for _, downloadedPlugin := range downloadedPlugins {
-					if dependency.Name == downloadedPlugin {
-						found = true
-						dependency.OtherPlugin = true
-						break
+		dependency, found := resolved[request.name]
+		if !found {
+			dependency = getDependency(resolved, request.name)
+
+			// See if it's bundled inside one of the other mods
+			if _, bundled := bundledNames[dependency.Name]; bundled {
+				dependency.Bundled = true
+			} else {
+
+				// If it's still not found; we will attempt to download it
+				fmt.Printf("Missing dependency: %s requires %s\n", session.Links[request.linkId].Link, dependency.Name)
+				c.downloadDependency(ctx, session, mode, dependency)
+
+				// The dependencies of the new JAR have to be checked as well
+				if dependency.Download != nil && dependency.Download.Status == Success {
+					if jar, err := parse(session, dependency.Download.Path); err == nil {
+						resolvedDepends[dependency] = jar.Depends
+
+						for _, name := range jar.Names {
+							if _, exists := resolved[strings.ToLower(name)]; !exists {
+								resolved[strings.ToLower(name)] = dependency
+							}
+						}
+
+						for _, name := range jar.Bundled {
+							if _, exists := bundledNames[strings.ToLower(name)]; !exists {
+								bundledNames[strings.ToLower(name)] = request.linkId
+							}
+						}
 					}
+				} else {
+					dependency.Chain = chain
 				}
 			}
+		}
 
-			// If it's still not found; we will attempt to download it
-			if !found {
-				fmt.Printf("Missing dependency: Plugin %s requires %s\n", downloadedPlugins[parentId], dependency)
+		dependency.addParent(request.chain[len(request.chain)-1])
+		linkDependencies[request.linkId] = append(linkDependencies[request.linkId], dependency.Name)
 
-				searchResult := c.getPluginInformation(session, getPluginInformationOptions{checkWithName: true, name: dependency.Name})
-				dependency.Search = &searchResult
+		for _, name := range resolvedDepends[dependency] {
+			queue = append(queue, dependencyRequest{linkId: request.linkId, name: strings.ToLower(name), chain: chain})
+		}
+	}
 
-				if dependency.Search.Status == Success {
+	// Soft dependencies are not needed, but the user can choose to add the missing ones
+	for linkId, jar := range descriptors {
+		suggested := make(map[string]bool)
+		for _, name := range jar.SoftDepends {
+			name = strings.ToLower(name)
+			if _, isLink := linkNames[name]; isLink || suggested[name] {
+				continue
+			}
 
-					// Try each link until one succeeds or we run out
-					for availableLink := range dependency.Search.Links {
+			if _, bundled := bundledNames[name]; bundled {
+				continue
+			}
 
-						// Let's give it a name
-						fileName := dependency.Name
-						if dependency.Search.PluginInfo != nil {
-							fileName = dependency.Search.PluginInfo.Name
-						}
+			if _, found := resolved[name]; found {
+				continue
+			}
 
-						// Download and verify the JAR
-						downloadResult := c.downloadAndVerifyJar(availableLink, session.DownloadsDirectory, fileName+".jar")
-						dependency.Download = &downloadResult
+			suggested[name] = true
+			state := session.Links[linkId]
+			state.PostProcessing.Suggestions = append(state.PostProcessing.Suggestions, Dependency{
+				Name:    name,
+				Parents: []string{providedNames[linkId][0]},
+			})
+		}
+	}
 
-						// Store it, so we don't download it again
-						downloadedDependencies[dependencyName] = dependency
-					}
-				}
+	for linkId, names := range linkDependencies {
+		dependencies := make([]Dependency, 0)
+		added := make(map[string]bool)
+		for _, name := range names {
+			if added[name] {
+				continue
 			}
+			added[name] = true
 
-			dependencies = append(dependencies, dependency)
+			dependencies = append(dependencies, *resolved[name])
 		}
 
-		state := PostProcessing{Dependencies: dependencies}
-		session.Links[parentId].PostProcessing = &state
+		session.Links[linkId].PostProcessing.Dependencies = dependencies
 	}
+
+	return
 }
 
-// Package finalizes the files
-func (c *Checker) Package(session *Session) {
-	switch session.Request.Mode {
-	case Plugins:
+// AcceptSuggestion downloads one of the soft dependencies that were
+// suggested for a link and moves it to the link's dependencies
+func (c *Checker) AcceptSuggestion(ctx context.Context, session *Session, linkId uuid.UUID, name string) (err error) {
+	state, ok := session.Links[linkId]
+	if !ok || state.PostProcessing == nil || state.Preliminary == nil {
+		err = fmt.Errorf("link not found")
+		return
+	}
+
+	index := -1
+	for i, suggestion := range state.PostProcessing.Suggestions {
+		if suggestion.Name == strings.ToLower(name) {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		err = fmt.Errorf("suggestion not found")
+		return
+	}
+
+	dependency := &state.PostProcessing.Suggestions[index]
+	c.downloadDependency(ctx, session, state.Preliminary.Mode, dependency)
+	if dependency.Search.Status != Success {
+		err = fmt.Errorf("unable to find %s: %s", dependency.Name, dependency.Search.Message)
+		return
+	}
+
+	if dependency.Download == nil || dependency.Download.Status != Success {
+		err = fmt.Errorf("unable to download %s", dependency.Name)
+		return
+	}
+
+	state.PostProcessing.Dependencies = append(state.PostProcessing.Dependencies, *dependency)
+	state.PostProcessing.Suggestions = append(state.PostProcessing.Suggestions[:index], state.PostProcessing.Suggestions[index+1:]...)
+	return
+}
+
+// getDependency returns the dependency with the
+// passed name, or creates it if it was not found yet
+func getDependency(resolved map[string]*Dependency, name string) *Dependency {
+	if dependency, found := resolved[name]; found {
+		return dependency
+	}
+
+	dependency := &Dependency{Name: name}
+	resolved[name] = dependency
+	return dependency
+}
+
+// addParent records the name of a JAR that requires the dependency
+func (d *Dependency) addParent(name string) {
+	if indexOf(d.Parents, name) == -1 {
+		d.Parents = append(d.Parents, name)
+	}
+}
+
+// downloadDependency searches for a missing dependency by its name and attempts to download it
+func (c *Checker) downloadDependency(ctx context.Context, session *Session, mode modeType, dependency *Dependency) {
+	searchResult := c.getPluginInformation(ctx, session, getPluginInformationOptions{checkWithName: true, name: dependency.Name, mode: mode})
+	dependency.Search = &searchResult
+	if dependency.Search.Status != Success {
+		return
+	}
+
+	// Try each link until one succeeds or we run out
+	c.Pool.Run(ctx, session.Id, func() {
+		for availableLink := range dependency.Search.Links {
+
+			// Let's give it a name
+			name := fileName{Fallback: dependency.Name, Extension: ".jar"}
+			if dependency.Search.PluginInfo != nil {
+				name.Fallback = dependency.Search.PluginInfo.Name
+			}
+
+			if version := dependency.Search.Version; version != nil && version.URL == availableLink {
+				name.Provided = version.FileName
+			}
+
+			// Download and verify the JAR
+			downloadResult := c.downloadAndVerifyJar(ctx, session, availableLink, session.getTargetDirectory(mode), name, nil)
+			dependency.Download = &downloadResult
+			if downloadResult.Status == Success {
+				break
+			}
+		}
+	})
+}
+
+// checkConflicts goes through each link and reports the ones
+// that provide the same names, or that are declared incompatible
+// with each other, either by their descriptor or by their provider
+// Soft incompatibilities are only reported as warnings
+func checkConflicts(session *Session, descriptors map[uuid.UUID]descriptor) {
+	reported := make(map[string]bool)
+	addConflict := func(linkId, otherId uuid.UUID, errorType sockets.ErrorType, message string) {
+		key := fmt.Sprintf("%s/%s/%s", linkId, otherId, errorType)
+		if linkId == otherId || reported[key] {
+			return
+		}
+		reported[key] = true
+
+		state := session.Links[linkId]
+		if state.PostProcessing == nil {
+			state.PostProcessing = &PostProcessing{}
+		}
+
+		state.PostProcessing.Conflicts = append(state.PostProcessing.Conflicts, Conflict{
+			Type:    errorType,
+			Link:    otherId,
+			Message: message,
+		})
+	}
+
+	addWarning := func(linkId, otherId uuid.UUID, message string) {
+		key := fmt.Sprintf("%s/%s/%s", linkId, otherId, sockets.SoftIncompatible)
+		if linkId == otherId || reported[key] {
+			return
+		}
+		reported[key] = true
+
+		state := session.Links[linkId]
+		if state.PostProcessing == nil {
+			state.PostProcessing = &PostProcessing{}
+		}
+
+		state.PostProcessing.Warnings = append(state.PostProcessing.Warnings, Issue{
+			Type:    sockets.SoftIncompatible,
+			Message: message,
+		})
+	}
+
+	// Find out which links provide each name
+	providedBy := make(map[string][]uuid.UUID)
+	for linkId, jar := range descriptors {
+		for _, name := range jar.Names {
+			name = strings.ToLower(name)
+			providedBy[name] = append(providedBy[name], linkId)
+		}
+	}
+
+	// Two links that provide the same name will overwrite each other
+	for name, linkIds := range providedBy {
+		for _, linkId := range linkIds {
+			for _, otherId := range linkIds {
+				addConflict(linkId, otherId, sockets.DuplicateName, fmt.Sprintf("%s is also provided by %s", name, session.Links[otherId].Link))
+			}
+		}
+	}
+
+	// Incompatibilities declared by the descriptors
+	for linkId, jar := range descriptors {
+		for _, name := range jar.Breaks {
+			for _, otherId := range providedBy[strings.ToLower(name)] {
+				addConflict(linkId, otherId, sockets.Incompatible, fmt.Sprintf("incompatible with %s", name))
+				addConflict(otherId, linkId, sockets.Incompatible, fmt.Sprintf("%s is incompatible with it", session.Links[linkId].Link))
+			}
+		}
+
+		for _, name := range jar.Conflicts {
+			for _, otherId := range providedBy[strings.ToLower(name)] {
+				addWarning(linkId, otherId, fmt.Sprintf("may not work well with %s (%s)", name, session.Links[otherId].Link))
+				addWarning(otherId, linkId, fmt.Sprintf("%s may not work well with it", session.Links[linkId].Link))
+			}
+		}
+	}
 
-		pack := Package{
-			Session: session,
-			Status:  Success,
-			Name:    "Plugin Pack",
-			Type:    Server,
+	// Incompatibilities declared by the providers, which are done by project IDs
+	projects := make(map[string][]uuid.UUID)
+	for linkId, state := range session.Links {
+		if state.Preliminary != nil && state.Preliminary.PluginInfo != nil && state.Download != nil && state.Download.Status == Success {
+			key := fmt.Sprintf("%s/%s", state.Preliminary.PluginInfo.Type, state.Preliminary.PluginInfo.Id)
+			projects[key] = append(projects[key], linkId)
 		}
+	}
 
-		// Create a ZIP
-		info, err := utils.ZipFolder(path.Join(session.WorkingDirectory, "pack.zip"), session.DownloadsDirectory)
+	for linkId, state := range session.Links {
+		if state.Preliminary == nil || state.Preliminary.PluginInfo == nil || state.Preliminary.Version == nil || state.Download == nil || state.Download.Status != Success {
+			continue
+		}
+
+		for _, projectId := range state.Preliminary.Version.Incompatible {
+			key := fmt.Sprintf("%s/%s", state.Preliminary.PluginInfo.Type, projectId)
+			for _, otherId := range projects[key] {
+				other := session.Links[otherId].Preliminary.PluginInfo.Name
+				addConflict(linkId, otherId, sockets.Incompatible, fmt.Sprintf("incompatible with %s", other))
+				addConflict(otherId, linkId, sockets.Incompatible, fmt.Sprintf("%s is incompatible with it", state.Preliminary.PluginInfo.Name))
+			}
+		}
+	}
+}
+
+// downloadLibraries resolves the Maven libraries declared by every downloaded
+// plugin and downloads them into the same layout the server would,
+// so it does not have to download them on its first startup
+func (c *Checker) downloadLibraries(ctx context.Context, session *Session) {
+	session.Libraries = &Libraries{Artifacts: make([]providers.MavenArtifact, 0)}
+
+	artifacts := make([]providers.MavenArtifact, 0)
+	added := make(map[string]bool)
+	pluginsDirectory := session.getTargetDirectory(Plugins)
+	for _, file := range session.getPackFiles() {
+		if path.Dir(file.Download.Path) != pluginsDirectory {
+			continue
+		}
+
+		plugin, err := session.Request.Platform.getPluginParser()(file.Download.Path)
 		if err != nil {
-			pack.Status = Error
-			pack.Message = err.Error()
-		} else {
-			pack.Size = info.Size
-			pack.Path = info.Path
+			continue
+		}
+
+		for _, library := range plugin.Libraries {
+			artifact, err := providers.ParseMavenArtifact(library)
+			if err != nil {
+				session.Libraries.Errors = append(session.Libraries.Errors, fmt.Sprintf("%s: %s", plugin.Name, err))
+				continue
+			}
+
+			if !added[artifact.String()] {
+				added[artifact.String()] = true
+				artifacts = append(artifacts, artifact)
+			}
+		}
+	}
+
+	if len(artifacts) == 0 {
+		return
+	}
+
+	resolution := c.Maven.ResolveLibraries(ctx, artifacts)
+	session.Libraries.Artifacts = resolution.Artifacts
+	session.Libraries.Errors = append(session.Libraries.Errors, resolution.Errors...)
+
+	var errorsLock sync.Mutex
+	librariesDirectory := path.Join(session.DownloadsDirectory, "libraries")
+	jobs := make([]func(), 0)
+	for _, file := range resolution.Files {
+		file := file
+		jobs = append(jobs, func() {
+			if err := c.downloadLibraryFile(ctx, session, librariesDirectory, file); err != nil {
+				errorsLock.Lock()
+				session.Libraries.Errors = append(session.Libraries.Errors, fmt.Sprintf("%s: %s", file.Path, err))
+				errorsLock.Unlock()
+			}
+		})
+	}
+
+	c.Pool.Run(ctx, session.Id, jobs...)
+}
+
+// downloadLibraryFile downloads a single file of a Maven repository and verifies it
+func (c *Checker) downloadLibraryFile(ctx context.Context, session *Session, librariesDirectory string, file providers.MavenFile) error {
+	fullPath, err := utils.SafeJoin(librariesDirectory, file.Path)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.Maven.GetFile(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	// Libraries count towards the session as well
+	quota := c.newDownloadQuota(session)
+	if err = quota.setSize(int64(len(data))); err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(path.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(fullPath, data, 0644); err != nil {
+		quota.release()
+		return err
+	}
+
+	if err = utils.VerifyChecksum(fullPath, "sha1", file.Sha1); err != nil {
+		_ = os.Remove(fullPath)
+		quota.release()
+		return err
+	}
+
+	return nil
+}
+
+// checkJavaVersion determines the Java version the package needs from
+// every downloaded JAR and warns about the ones that need a newer
+// Java version than the one the game version normally runs on
+func checkJavaVersion(session *Session) {
+	expected, err := utils.GetGameJavaVersion(session.Request.GameVersion)
+	if err != nil || session.Request.Platform.isProxy() {
+		expected = 0
+	}
+
+	requirement := JavaRequirement{Expected: expected}
+	check := func(state *State, download *Download) {
+		if download == nil || download.Status != Success || download.JavaVersion == 0 {
+			return
+		}
+
+		if download.JavaVersion > requirement.Required {
+			requirement.Required = download.JavaVersion
+		}
+
+		if expected == 0 || download.JavaVersion <= expected {
+			return
+		}
+
+		if state.PostProcessing == nil {
+			state.PostProcessing = &PostProcessing{}
+		}
+
+		state.PostProcessing.Warnings = append(state.PostProcessing.Warnings, Issue{
+			Type:    sockets.NewerJava,
+			Message: fmt.Sprintf("%s needs Java %d, but %s normally runs on Java %d", path.Base(download.Path), download.JavaVersion, session.Request.GameVersion, expected),
+		})
+	}
+
+	for _, state := range session.Links {
+		check(state, state.Download)
+		if state.PostProcessing != nil {
+			for _, dependency := range state.PostProcessing.Dependencies {
+				check(state, dependency.Download)
+			}
 		}
+	}
+
+	if requirement.Required == 0 {
+		session.Java = nil
+		return
+	}
 
-		session.Packages = make(map[uuid.UUID]*Package)
-		session.Packages[uuid.New()] = &pack
-		break
+	if expected != 0 && requirement.Required > expected {
+		requirement.Warning = fmt.Sprintf("the pack needs Java %d, but %s normally runs on Java %d", requirement.Required, session.Request.GameVersion, expected)
+	}
+
+	session.Java = &requirement
+}
+
+// checkPackFormats warns about the data packs and the resource
+// packs that were not made for the requested game version
+func checkPackFormats(session *Session) {
+	for _, state := range session.Links {
+		if state.Download == nil || state.Download.Status != Success || state.Preliminary == nil || !state.Preliminary.Mode.isPack() {
+			continue
+		}
+
+		meta, err := utils.ParsePackMcmeta(state.Download.Path)
+		if err != nil {
+			continue
+		}
+
+		expected, known := utils.GetDatapackFormat(session.Request.GameVersion)
+		if state.Preliminary.Mode == ResourcePacks {
+			expected, known = utils.GetResourcePackFormat(session.Request.GameVersion)
+		}
+
+		if !known || meta.Supports(expected) {
+			continue
+		}
+
+		if state.PostProcessing == nil {
+			state.PostProcessing = &PostProcessing{}
+		}
+
+		state.PostProcessing.Warnings = append(state.PostProcessing.Warnings, Issue{
+			Type:    sockets.PackFormat,
+			Message: fmt.Sprintf("the pack was made for %s, but %s uses format %d", meta, session.Request.GameVersion, expected),
+		})
+	}
+}
+
+// Package finalizes the files
+func (c *Checker) Package(session *Session) {
+	pack := Package{
+		Session: session,
+		Status:  Success,
+		Name:    session.Request.Mode.getPackageName(),
+		Type:    Server,
+	}
+
+	// Create a ZIP, without the downloads that were never finished
+	info, err := utils.ZipFolder(path.Join(session.WorkingDirectory, "pack.zip"), session.DownloadsDirectory, func(fullPath string) bool {
+		return strings.HasSuffix(fullPath, partSuffix)
+	})
+	if err != nil {
+		pack.Status = Error
+		pack.Message = err.Error()
+	} else {
+		pack.Size = info.Size
+		pack.Path = info.Path
+	}
+
+	session.Packages = make(map[uuid.UUID]*Package)
+	session.Packages[uuid.New()] = &pack
+
+	// Client packs can only be created for mod loaders
+	if session.Request.Mode == Mods {
+		session.Packages[uuid.New()] = c.exportPack(session, "Modrinth Pack", "pack.mrpack", writeModrinthPack)
+		session.Packages[uuid.New()] = c.exportPack(session, "CurseForge Pack", "pack-curseforge.zip", writeCurseForgePack)
+		session.Packages[uuid.New()] = c.exportPack(session, "packwiz Pack", "pack-packwiz.zip", writePackwizPack)
+		session.Packages[uuid.New()] = c.exportPack(session, "Prism Launcher Instance", "instance.zip", writeInstancePack)
 	}
 
 	session.OverallState.Package = true
@@ -790,7 +2104,19 @@ func (c *Checker) GetSupportInfo() utils.H {
 	fakeVersions := []string{"0.15.3", "0.15.2", "0.15.1"}
 	return utils.H{
 		"platforms": map[platformType]platformInfo{
-			Spigot:   {Name: "Spigot", GameVersions: []string{"1.8.8", "1.18.2", "1.20.4"}},
+			Spigot: {Name: "Spigot", GameVersions: []string{"1.8.8", "1.18.2", "1.20.4"}},
+			Paper:  {Name: "Paper", GameVersions: []string{"1.8.8", "1.12.2", "1.16.5", "1.18.2", "1.20.4"}, ServerJar: true},
+			Purpur: {Name: "Purpur", GameVersions: []string{"1.16.5", "1.18.2", "1.20.4"}, ServerJar: true},
+			Folia:  {Name: "Folia", GameVersions: []string{"1.19.4", "1.20.1", "1.20.2", "1.20.4"}, ServerJar: true},
+			Sponge: {Name: "Sponge", GameVersions: []string{"1.12.2", "1.16.5", "1.18.2", "1.19.4", "1.20.4"}},
+
+			Velocity:   {Name: "Velocity", GameVersions: versions, ServerJar: true},
+			BungeeCord: {Name: "BungeeCord", GameVersions: versions},
+			Waterfall:  {Name: "Waterfall", GameVersions: versions, ServerJar: true},
+
+			Mohist:   {Name: "Mohist", GameVersions: []string{"1.16.5", "1.18.2", "1.20.1"}},
+			Arclight: {Name: "Arclight", GameVersions: []string{"1.16.5", "1.18.2", "1.20.1", "1.20.4"}},
+
 			Fabric:   {Name: "Fabric", GameVersions: versions, PlatformVersions: fakeVersions},
 			Quilt:    {Name: "Quilt", GameVersions: versions, PlatformVersions: fakeVersions},
 			Forge:    {Name: "Forge", GameVersions: versions, PlatformVersions: fakeVersions},
@@ -0,0 +1,325 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"geri.dev/pack-builder/utils"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The suffix of files that are still being downloaded
+const partSuffix = ".part"
+
+// How long to wait before retrying a download the first time,
+// which doubles after each attempt up to maxRetryDelay
+const (
+	retryDelay    = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// fileName represents the names a downloaded file could be saved as
+type fileName struct {
+
+	// The name of the file according to the provider, if it specifies one
+	Provided string
+
+	// The name to use if neither the provider nor the server specify one
+	Fallback string
+
+	// The extension the file must end with
+	Extension string
+}
+
+// resolve returns the sanitized name the file should be saved as, preferring the name
+// from the provider, then the one the server sent and finally the fallback name
+func (fn fileName) resolve(serverName string) string {
+	name := ""
+	for _, candidate := range []string{fn.Provided, serverName, fn.Fallback} {
+		if name = utils.SanitizeFileName(candidate); name != "" {
+			break
+		}
+	}
+
+	if name == "" {
+		name = "download"
+	}
+
+	if !strings.HasSuffix(strings.ToLower(name), fn.Extension) {
+		name += fn.Extension
+	}
+
+	return name
+}
+
+// getPartPath returns where a link is downloaded to before we know its final name
+// It is based on the link, so an interrupted download can be found again later
+func getPartPath(folderPath, link string) string {
+	hash := sha1.Sum([]byte(link))
+	return path.Join(folderPath, fmt.Sprintf(".%x%s", hash[:8], partSuffix))
+}
+
+// lockPartFile ensures only one download writes to a partial file at a time, which
+// matters when the same link is added more than once. It returns a function to unlock it
+func (s *Session) lockPartFile(partPath string) func() {
+	s.fileNamesLock.Lock()
+	if s.partLocks == nil {
+		s.partLocks = make(map[string]*sync.Mutex)
+	}
+
+	lock, found := s.partLocks[partPath]
+	if !found {
+		lock = &sync.Mutex{}
+		s.partLocks[partPath] = lock
+	}
+	s.fileNamesLock.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// reserveFileName returns a path in the folder for a file with the passed name that is not
+// used by any other file downloaded for the session, adding a number to the name if needed
+func (s *Session) reserveFileName(folderPath, name string) string {
+	s.fileNamesLock.Lock()
+	defer s.fileNamesLock.Unlock()
+
+	if s.fileNames == nil {
+		s.fileNames = make(map[string]bool)
+	}
+
+	extension := path.Ext(name)
+	base := strings.TrimSuffix(name, extension)
+	fullPath := path.Join(folderPath, name)
+	for i := 2; s.isFileNameTaken(fullPath); i++ {
+		fullPath = path.Join(folderPath, fmt.Sprintf("%s-%d%s", base, i, extension))
+	}
+
+	s.fileNames[fullPath] = true
+	return fullPath
+}
+
+// isFileNameTaken returns whether a path is used by another file of the session, either one that
+// was downloaded, or one that is already on disk, such as the overrides of an imported pack
+// The files downloaded before the downloads were reset are not taken, they are replaced instead
+func (s *Session) isFileNameTaken(fullPath string) bool {
+	if s.fileNames[fullPath] {
+		return true
+	}
+
+	if s.replaceable[fullPath] {
+		return false
+	}
+
+	_, err := os.Stat(fullPath)
+	return err == nil
+}
+
+// releaseFileName makes the name of a file that was not kept available again
+func (s *Session) releaseFileName(fullPath string) {
+	s.fileNamesLock.Lock()
+	defer s.fileNamesLock.Unlock()
+
+	delete(s.fileNames, fullPath)
+}
+
+// discardDownload removes a downloaded file that turned out to be unusable,
+// so its name and its size no longer count towards the session
+func (s *Session) discardDownload(download *Download) {
+	_ = os.Remove(download.Path)
+	s.releaseFileName(download.Path)
+
+	s.usageLock.Lock()
+	s.usage -= download.Size
+	s.usageLock.Unlock()
+
+	download.Path = ""
+	download.FileName = ""
+}
+
+// resetDownloads starts keeping track of the names and the sizes of the files downloaded for the
+// session again. If keep is true, the files recorded in its state are still in use, otherwise
+// they may be replaced by the new downloads
+func (s *Session) resetDownloads(keep bool) {
+	s.fileNamesLock.Lock()
+	defer s.fileNamesLock.Unlock()
+	s.usageLock.Lock()
+	defer s.usageLock.Unlock()
+
+	// Either the files we have are still in use, or they are about to be replaced
+	downloaded := make(map[string]bool)
+	var size int64
+	for relPath, file := range s.getPackFiles() {
+		downloaded[path.Join(s.DownloadsDirectory, relPath)] = true
+		size += file.Download.Size
+	}
+
+	if s.Server != nil && s.Server.Path != "" {
+		downloaded[s.Server.Path] = true
+		size += s.Server.Size
+	}
+
+	if keep {
+		s.fileNames = downloaded
+		s.replaceable = make(map[string]bool)
+		s.usage = size
+	} else {
+		s.fileNames = make(map[string]bool)
+		s.replaceable = downloaded
+		s.usage = 0
+	}
+
+	// The overrides of an imported pack are not downloaded again, so they always count
+	s.usage += s.OverridesSize
+}
+
+// downloadFile downloads a link to a partial file, retrying with an increasing delay
+// when the error seems temporary, and continuing from where the previous attempt left off
+// It returns the name of the file if the server specified one
+func (c *Checker) downloadFile(ctx context.Context, link, partPath string, quota *downloadQuota, progress func(received, total int64)) (serverName string, err error) {
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		var transient bool
+		serverName, transient, err = c.downloadAttempt(ctx, link, partPath, quota, progress)
+		if err == nil || !transient || attempt >= c.DownloadRetries {
+			return
+		}
+
+		fmt.Printf("Unable to download %s, retrying in %s: %s\n", link, delay, err)
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// downloadAttempt downloads a link to a partial file once, continuing the file if some of it
+// was already downloaded. It returns the name of the file if the server specified one,
+// and whether the error is temporary, so it's worth trying again
+func (c *Checker) downloadAttempt(ctx context.Context, link, partPath string, quota *downloadQuota, progress func(received, total int64)) (serverName string, transient bool, err error) {
+
+	// Continue where we left off if we have some of the file already
+	var offset int64
+	if info, statErr := os.Stat(partPath); statErr == nil {
+		offset = info.Size()
+	}
+
+	// A single request can only take so long, after which it counts as going over the quota
+	requestCtx := ctx
+	if timeout := c.Quotas.GetRequestTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		requestCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		defer func() {
+			if err != nil && ctx.Err() == nil && requestCtx.Err() == context.DeadlineExceeded {
+				err = &quotaError{fmt.Sprintf("the download took longer than the limit of %s", timeout)}
+				transient = false
+			}
+		}()
+	}
+
+	req, err := http.NewRequestWithContext(requestCtx, "GET", link, nil)
+	if err != nil {
+		return
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		transient = ctx.Err() == nil && !utils.IsBlocked(err)
+		return
+	}
+
+	defer resp.Body.Close()
+
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		serverName = path.Base(params["filename"])
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+
+		// Make sure the server continues exactly where we left off
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			_ = os.Remove(partPath)
+			transient = true
+			err = fmt.Errorf("unexpected range: %s", resp.Header.Get("Content-Range"))
+			return
+		}
+		flags |= os.O_APPEND
+
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+
+		// The partial file does not belong to the current file anymore, so we will start over
+		_ = os.Remove(partPath)
+		return c.downloadAttempt(ctx, link, partPath, quota, progress)
+
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+
+		// The server does not support ranges, so we have to start over
+		flags |= os.O_TRUNC
+		offset = 0
+
+	default:
+		transient = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return
+	}
+
+	// Count what we already have, and check the size of the whole file
+	// before we download it, if the server tells us how large it is
+	if err = quota.setSize(offset); err != nil {
+		return
+	}
+
+	if resp.ContentLength > 0 {
+		if err = quota.check(offset + resp.ContentLength); err != nil {
+			return
+		}
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return
+	}
+
+	defer out.Close()
+
+	// Write the body to the file, reporting the progress of the whole file
+	// and stopping as soon as it goes over the quota
+	writer := &quotaWriter{writer: out, quota: quota, size: offset}
+	var body io.Reader = resp.Body
+	if progress != nil {
+		total := resp.ContentLength
+		if total > 0 {
+			total += offset
+		}
+		body = &progressReader{reader: resp.Body, received: offset, total: total, report: progress}
+	}
+
+	if _, err = io.Copy(writer, body); err != nil {
+		transient = ctx.Err() == nil && !isQuotaExceeded(err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,623 @@
+package checker
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"geri.dev/pack-builder/providers"
+	"geri.dev/pack-builder/utils"
+	"github.com/BurntSushi/toml"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// packFile represents a single file that was downloaded
+// for the session, along with what we know about its project
+type packFile struct {
+	Download *Download
+	Info     *providers.PluginInfo
+	Version  *providers.Version
+}
+
+// getPackFiles returns every file that was successfully downloaded for
+// the session, keyed by their path relative to the root of the package
+func (s *Session) getPackFiles() map[string]packFile {
+	files := make(map[string]packFile)
+	add := func(download *Download, preliminary *Preliminary) {
+		if download == nil || download.Status != Success {
+			return
+		}
+
+		relPath, err := filepath.Rel(s.DownloadsDirectory, download.Path)
+		if err != nil {
+			return
+		}
+
+		file := packFile{Download: download}
+		if preliminary != nil {
+			file.Info = preliminary.PluginInfo
+			file.Version = preliminary.Version
+		}
+
+		files[filepath.ToSlash(relPath)] = file
+	}
+
+	for _, state := range s.Links {
+		add(state.Download, state.Preliminary)
+		if state.PostProcessing != nil {
+			for _, dependency := range state.PostProcessing.Dependencies {
+				add(dependency.Download, dependency.Search)
+			}
+		}
+	}
+
+	return files
+}
+
+// getName returns the name of the pack, which is either the
+// one from an imported modpack or the generic name of the package
+func (request *Request) getName() string {
+	if request.Name != "" {
+		return request.Name
+	}
+	return request.Mode.getPackageName()
+}
+
+// packArchive is a ZIP archive that an export is written into
+type packArchive struct {
+	writer *zip.Writer
+}
+
+// WriteJSON writes a value as a JSON document into the archive
+func (a *packArchive) WriteJSON(name string, data interface{}) error {
+	bytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return a.WriteBytes(name, bytes)
+}
+
+// WriteBytes writes a file with the given content into the archive
+func (a *packArchive) WriteBytes(name string, data []byte) error {
+	writer, err := a.writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(data)
+	return err
+}
+
+// WriteFile copies a file from the disk into the archive
+func (a *packArchive) WriteFile(name, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer, err := a.writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+// writePackArchive creates a new archive and lets the passed function fill it
+func writePackArchive(filePath string, write func(*packArchive) error) (info utils.ZipInfo, err error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	archive := packArchive{writer: zip.NewWriter(file)}
+	if err = write(&archive); err != nil {
+		_ = archive.writer.Close()
+		return
+	}
+
+	// Close the zip writer to ensure all data is written
+	if err = archive.writer.Close(); err != nil {
+		return
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	info = utils.ZipInfo{
+		Path: file.Name(),
+		Size: fileInfo.Size(),
+	}
+
+	return
+}
+
+// serverOnlyFiles are the files in the root of a package that only a server uses
+var serverOnlyFiles = map[string]bool{
+	"server.properties":   true,
+	"eula.txt":            true,
+	"ops.json":            true,
+	"whitelist.json":      true,
+	"banned-players.json": true,
+	"banned-ips.json":     true,
+	"usercache.json":      true,
+}
+
+// isServerOnlyFile returns whether a file in the package that we did not download
+// only belongs on the server, either because of its name, or because it came from
+// the server overrides of an imported pack
+func (s *Session) isServerOnlyFile(relPath string) bool {
+	return serverOnlyFiles[relPath] || s.ServerOverrides[relPath]
+}
+
+// walkPackage calls the passed function for each file in the package, except
+// for the server JAR and unfinished downloads, with their path relative to the root of the package
+func walkPackage(session *Session, walk func(relPath, fullPath string) error) error {
+	return filepath.Walk(session.DownloadsDirectory, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		if session.Server != nil && session.Server.Path == fullPath {
+			return nil
+		}
+
+		if strings.HasSuffix(fullPath, partSuffix) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(session.DownloadsDirectory, fullPath)
+		if err != nil {
+			return err
+		}
+
+		return walk(filepath.ToSlash(relPath), fullPath)
+	})
+}
+
+// exportPack creates an additional client package for the session
+// using the passed function to write the contents of the archive
+func (c *Checker) exportPack(session *Session, name, fileName string, write func(*Session, *packArchive) error) *Package {
+	pack := Package{
+		Session: session,
+		Status:  Success,
+		Name:    name,
+		Type:    Client,
+	}
+
+	info, err := writePackArchive(path.Join(session.WorkingDirectory, fileName), func(archive *packArchive) error {
+		return write(session, archive)
+	})
+
+	if err != nil {
+		pack.Status = Error
+		pack.Message = err.Error()
+	} else {
+		pack.Size = info.Size
+		pack.Path = info.Path
+	}
+
+	return &pack
+}
+
+// modrinthPackDomains are the only hosts a .mrpack is allowed to download from
+var modrinthPackDomains = []string{"cdn.modrinth.com", "github.com", "raw.githubusercontent.com", "gitlab.com"}
+
+// isModrinthPackDownload returns true if the link
+// is allowed as a download in a .mrpack
+func isModrinthPackDownload(link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Scheme != "https" {
+		return false
+	}
+
+	for _, domain := range modrinthPackDomains {
+		if parsed.Host == domain {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getModrinthPackSide converts the side of a project
+// into one of the values a .mrpack accepts
+func getModrinthPackSide(side string) string {
+	switch side {
+	case "optional", "unsupported":
+		return side
+	}
+	return "required"
+}
+
+// writeModrinthPack writes a Modrinth .mrpack with the modrinth.index.json
+// listing each downloaded file that is hosted on an allowed domain,
+// and everything else from the package as overrides
+func writeModrinthPack(session *Session, archive *packArchive) error {
+	index := modrinthPackIndex{
+		FormatVersion: 1,
+		Game:          "minecraft",
+		VersionId:     "1.0.0",
+		Name:          session.Request.getName(),
+		Files:         make([]modrinthPackFile, 0),
+		Dependencies: map[string]string{
+			"minecraft": session.Request.GameVersion,
+		},
+	}
+
+	for dependency, platform := range modrinthPackLoaders {
+		if platform == session.Request.Platform {
+			index.Dependencies[dependency] = session.Request.PlatformVersion
+		}
+	}
+
+	files := session.getPackFiles()
+	err := walkPackage(session, func(relPath, fullPath string) error {
+		file, downloaded := files[relPath]
+
+		// Anything that we did not download ourselves is shipped as is
+		if !downloaded {
+			folder := "overrides"
+			if session.isServerOnlyFile(relPath) {
+				folder = "server-overrides"
+			}
+
+			return archive.WriteFile(folder+"/"+relPath, fullPath)
+		}
+
+		env := modrinthPackEnv{Client: "required", Server: "required"}
+		if file.Info != nil {
+			env.Client = getModrinthPackSide(file.Info.ClientSide)
+			env.Server = getModrinthPackSide(file.Info.ServerSide)
+		}
+
+		// If the launcher is not allowed to download it, we will have to ship it
+		if !isModrinthPackDownload(file.Download.URL) {
+			folder := "overrides"
+			if env.Client == "unsupported" {
+				folder = "server-overrides"
+			} else if env.Server == "unsupported" {
+				folder = "client-overrides"
+			}
+
+			return archive.WriteFile(folder+"/"+relPath, fullPath)
+		}
+
+		hashes, err := utils.HashFile(fullPath, "sha1", "sha512")
+		if err != nil {
+			return err
+		}
+
+		index.Files = append(index.Files, modrinthPackFile{
+			Path:      relPath,
+			Hashes:    hashes,
+			Env:       &env,
+			Downloads: []string{file.Download.URL},
+			FileSize:  file.Download.Size,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(index.Files, func(i, j int) bool {
+		return index.Files[i].Path < index.Files[j].Path
+	})
+
+	return archive.WriteJSON("modrinth.index.json", index)
+}
+
+// writeCurseForgePack writes a CurseForge modpack with the manifest.json
+// listing each downloaded file that is hosted on CurseForge,
+// and everything else from the package as overrides
+func writeCurseForgePack(session *Session, archive *packArchive) error {
+	manifest := curseForgePackManifest{
+		ManifestType:    "minecraftModpack",
+		ManifestVersion: 1,
+		Name:            session.Request.getName(),
+		Version:         "1.0.0",
+		Files:           make([]curseForgePackFile, 0),
+		Overrides:       "overrides",
+	}
+
+	manifest.Minecraft.Version = session.Request.GameVersion
+	manifest.Minecraft.ModLoaders = []curseForgePackLoader{{
+		Id:      fmt.Sprintf("%s-%s", session.Request.Platform, session.Request.PlatformVersion),
+		Primary: true,
+	}}
+
+	files := session.getPackFiles()
+	err := walkPackage(session, func(relPath, fullPath string) error {
+		file, downloaded := files[relPath]
+
+		// CurseForge packs do not differentiate between sides,
+		// so we will leave out anything the client can't load
+		if downloaded && file.Info != nil && file.Info.ClientSide == "unsupported" {
+			return nil
+		}
+
+		if !downloaded && session.isServerOnlyFile(relPath) {
+			return nil
+		}
+
+		// Anything that is not from CurseForge is shipped as is
+		if !downloaded || file.Info == nil || file.Info.Type != providers.CurseForge || file.Version == nil {
+			return archive.WriteFile(manifest.Overrides+"/"+relPath, fullPath)
+		}
+
+		projectId, err := strconv.ParseInt(file.Info.Id, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		fileId, err := strconv.ParseInt(file.Version.Id, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		manifest.Files = append(manifest.Files, curseForgePackFile{
+			ProjectId: projectId,
+			FileId:    fileId,
+			Required:  true,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].ProjectId < manifest.Files[j].ProjectId
+	})
+
+	return archive.WriteJSON("manifest.json", manifest)
+}
+
+// getPackwizSide converts the sides of a project into a packwiz side
+func getPackwizSide(info *providers.PluginInfo) string {
+	if info == nil {
+		return "both"
+	}
+
+	if info.ClientSide == "unsupported" {
+		return "server"
+	}
+
+	if info.ServerSide == "unsupported" {
+		return "client"
+	}
+
+	return "both"
+}
+
+// encodeToml encodes a value as a TOML document
+func encodeToml(data interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := toml.NewEncoder(&buffer).Encode(data); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// writePackwizPack writes a packwiz pack with a .pw.toml metafile for each
+// downloaded file, with update metadata for Modrinth and CurseForge projects,
+// and everything else from the package as regular files of the pack
+func writePackwizPack(session *Session, archive *packArchive) error {
+	index := packwizIndex{
+		HashFormat: "sha256",
+		Files:      make([]packwizIndexFile, 0),
+	}
+
+	files := session.getPackFiles()
+	err := walkPackage(session, func(relPath, fullPath string) error {
+		file, downloaded := files[relPath]
+
+		hashes, err := utils.HashFile(fullPath, "sha1", "sha256")
+		if err != nil {
+			return err
+		}
+
+		// Anything that we did not download ourselves is shipped as is
+		if !downloaded {
+			index.Files = append(index.Files, packwizIndexFile{File: relPath, Hash: hashes["sha256"]})
+			return archive.WriteFile(relPath, fullPath)
+		}
+
+		metafile := packwizMetafile{
+			Name:     strings.TrimSuffix(path.Base(relPath), path.Ext(relPath)),
+			FileName: path.Base(relPath),
+			Side:     getPackwizSide(file.Info),
+		}
+
+		if file.Info != nil && file.Info.Name != "" {
+			metafile.Name = file.Info.Name
+		}
+
+		metafile.Download.Url = file.Download.URL
+		metafile.Download.HashFormat = "sha256"
+		metafile.Download.Hash = hashes["sha256"]
+
+		if file.Info != nil && file.Version != nil {
+			switch file.Info.Type {
+			case providers.Modrinth:
+				metafile.Update.Modrinth = &packwizModrinthUpdate{
+					ModId:   file.Info.Id,
+					Version: file.Version.Id,
+				}
+			case providers.CurseForge:
+				projectId, err := strconv.ParseInt(file.Info.Id, 10, 64)
+				if err != nil {
+					return err
+				}
+
+				fileId, err := strconv.ParseInt(file.Version.Id, 10, 64)
+				if err != nil {
+					return err
+				}
+
+				// CurseForge does not allow direct links,
+				// so packwiz resolves them from the metadata
+				metafile.Update.CurseForge = &packwizCurseForgeUpdate{FileId: fileId, ProjectId: projectId}
+				metafile.Download.Url = ""
+				metafile.Download.Mode = "metadata:curseforge"
+				metafile.Download.HashFormat = "sha1"
+				metafile.Download.Hash = hashes["sha1"]
+			}
+		}
+
+		data, err := encodeToml(metafile)
+		if err != nil {
+			return err
+		}
+
+		metafilePath := strings.TrimSuffix(relPath, path.Ext(relPath)) + ".pw.toml"
+		index.Files = append(index.Files, packwizIndexFile{
+			File:     metafilePath,
+			Hash:     fmt.Sprintf("%x", sha256.Sum256(data)),
+			Metafile: true,
+		})
+
+		return archive.WriteBytes(metafilePath, data)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(index.Files, func(i, j int) bool {
+		return index.Files[i].File < index.Files[j].File
+	})
+
+	indexData, err := encodeToml(index)
+	if err != nil {
+		return err
+	}
+
+	if err = archive.WriteBytes("index.toml", indexData); err != nil {
+		return err
+	}
+
+	pack := packwizPack{
+		Name:       session.Request.getName(),
+		PackFormat: "packwiz:1.1.0",
+		Version:    "1.0.0",
+		Index: packwizIndexReference{
+			File:       "index.toml",
+			HashFormat: "sha256",
+			Hash:       fmt.Sprintf("%x", sha256.Sum256(indexData)),
+		},
+		Versions: map[string]string{
+			"minecraft": session.Request.GameVersion,
+		},
+	}
+
+	for loader, platform := range packwizLoaders {
+		if platform == session.Request.Platform {
+			pack.Versions[loader] = session.Request.PlatformVersion
+		}
+	}
+
+	packData, err := encodeToml(pack)
+	if err != nil {
+		return err
+	}
+
+	return archive.WriteBytes("pack.toml", packData)
+}
+
+// mmcPackComponent represents a component of a Prism Launcher / MultiMC instance
+type mmcPackComponent struct {
+	Uid       string `json:"uid"`
+	Version   string `json:"version"`
+	Important bool   `json:"important,omitempty"`
+}
+
+// mmcPack represents the mmc-pack.json of a Prism Launcher / MultiMC instance
+type mmcPack struct {
+	Components    []mmcPackComponent `json:"components"`
+	FormatVersion int                `json:"formatVersion"`
+}
+
+// mmcPackLoaders maps our platforms to the uid of their instance component
+var mmcPackLoaders = map[platformType]string{
+	Fabric:   "net.fabricmc.fabric-loader",
+	Quilt:    "org.quiltmc.quilt-loader",
+	Forge:    "net.minecraftforge",
+	NeoForge: "net.neoforged",
+}
+
+// writeInstancePack writes a Prism Launcher / MultiMC instance
+// with every file of the package the client is able to load
+func writeInstancePack(session *Session, archive *packArchive) error {
+	pack := mmcPack{
+		Components: []mmcPackComponent{
+			{Uid: "net.minecraft", Version: session.Request.GameVersion, Important: true},
+		},
+		FormatVersion: 1,
+	}
+
+	// Fabric and Quilt need the intermediary mappings of the game version
+	switch session.Request.Platform {
+	case Fabric, Quilt:
+		pack.Components = append(pack.Components, mmcPackComponent{Uid: "net.fabricmc.intermediary", Version: session.Request.GameVersion})
+	}
+
+	if uid, ok := mmcPackLoaders[session.Request.Platform]; ok {
+		pack.Components = append(pack.Components, mmcPackComponent{Uid: uid, Version: session.Request.PlatformVersion})
+	}
+
+	if err := archive.WriteJSON("mmc-pack.json", pack); err != nil {
+		return err
+	}
+
+	config := fmt.Sprintf("InstanceType=OneSix\nname=%s\n", session.Request.getName())
+	if err := archive.WriteBytes("instance.cfg", []byte(config)); err != nil {
+		return err
+	}
+
+	files := session.getPackFiles()
+	datapacks, err := filepath.Rel(session.DownloadsDirectory, session.getTargetDirectory(Datapacks))
+	if err != nil {
+		return err
+	}
+
+	return walkPackage(session, func(relPath, fullPath string) error {
+
+		// Data packs belong to a world, which the instance does not have
+		if strings.HasPrefix(relPath, filepath.ToSlash(datapacks)+"/") {
+			return nil
+		}
+
+		// Leave out anything that only works on the server, including the overrides that only belong there
+		file, downloaded := files[relPath]
+		if downloaded && file.Info != nil && file.Info.ClientSide == "unsupported" {
+			return nil
+		}
+
+		if !downloaded && session.isServerOnlyFile(relPath) {
+			return nil
+		}
+
+		return archive.WriteFile(".minecraft/"+relPath, fullPath)
+	})
+}
@@ -0,0 +1,594 @@
+package checker
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"geri.dev/pack-builder/utils"
+	"github.com/BurntSushi/toml"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var modrinthCdnRegex = regexp.MustCompile("https://cdn\\.modrinth\\.com/data/(?P<project>[^/]+)/versions/(?P<version>[^/]+)/")
+
+// PackSource provides access to the files of an existing modpack,
+// regardless of whether it was uploaded as an archive or is hosted online
+type PackSource interface {
+	// Open opens a file by its path relative to the root of the pack
+	Open(name string) (io.ReadCloser, error)
+
+	// List returns the paths of all the files inside a folder, if the source supports it
+	List(folder string) []string
+}
+
+// zipPackSource reads the files of a modpack from a ZIP archive, such as a .mrpack
+type zipPackSource struct {
+	reader  *zip.Reader
+	maxSize int64
+}
+
+// NewZipPackSource creates a new pack source from an archive
+// None of the files can be larger than maxSize, unless it's 0
+func NewZipPackSource(reader io.ReaderAt, size, maxSize int64) (PackSource, error) {
+	zipReader, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zipPackSource{reader: zipReader, maxSize: maxSize}, nil
+}
+
+func (zs *zipPackSource) Open(name string) (io.ReadCloser, error) {
+	file, err := zs.reader.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return newQuotaReadCloser(file, zs.maxSize, nil), nil
+}
+
+func (zs *zipPackSource) List(folder string) []string {
+	files := make([]string, 0)
+	for _, f := range zs.reader.File {
+		if strings.HasPrefix(f.Name, folder+"/") && !f.FileInfo().IsDir() {
+			files = append(files, f.Name)
+		}
+	}
+	return files
+}
+
+// remotePackSource reads the files of a modpack hosted online, such as a packwiz pack
+type remotePackSource struct {
+	base    *url.URL
+	c       *http.Client
+	maxSize int64
+	timeout time.Duration
+}
+
+// NewRemotePackSource creates a new pack source from a link
+// to the main file of the pack, such as a pack.toml
+// None of the files can be larger than maxSize or take longer than the timeout, unless they are 0
+func NewRemotePackSource(link string, c *http.Client, maxSize int64, timeout time.Duration) (PackSource, error) {
+	base, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid link")
+	}
+
+	return &remotePackSource{base: base, c: c, maxSize: maxSize, timeout: timeout}, nil
+}
+
+func (rs *remotePackSource) Open(name string) (io.ReadCloser, error) {
+	link, err := rs.base.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The timeout covers reading the file as well, so it's only cancelled once it's closed
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if rs.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, rs.timeout)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link.String(), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp, err := rs.c.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to get %s, status code: %d", name, resp.StatusCode)
+	}
+
+	if rs.maxSize > 0 && resp.ContentLength > rs.maxSize {
+		resp.Body.Close()
+		cancel()
+		return nil, &quotaError{fmt.Sprintf("%s is larger than the limit of %d MB", name, rs.maxSize/(1024*1024))}
+	}
+
+	return newQuotaReadCloser(resp.Body, rs.maxSize, cancel), nil
+}
+
+func (rs *remotePackSource) List(_ string) []string {
+	return nil
+}
+
+// readPackFile reads a whole file from a pack source
+func readPackFile(source PackSource, name string) ([]byte, error) {
+	rc, err := source.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// PackImport represents the result of importing an existing modpack
+type PackImport struct {
+	Name    string   `json:"name"`
+	Format  string   `json:"format"`
+	Skipped []string `json:"skipped"`
+}
+
+// packOverride represents a file from the pack that
+// is copied into the package as it is
+type packOverride struct {
+	Source string
+	Target string
+
+	// Whether the file only belongs on the server, so client packs can leave it out
+	ServerOnly bool
+}
+
+// importedPack represents everything we were able to parse from a modpack
+type importedPack struct {
+	PackImport
+	Request   Request
+	Overrides []packOverride
+}
+
+// addLink adds a link to the request if the file
+// it is for belongs in the folder of the platform's mode
+func (p *importedPack) addLink(filePath, link string) {
+	if !p.Request.Platform.isValid() || path.Dir(filePath) != string(p.Request.Platform.getMode()) {
+		p.Skipped = append(p.Skipped, filePath)
+		return
+	}
+
+	p.Request.Links[uuid.New().String()] = link
+}
+
+// addOverrides adds all the files of an overrides folder
+func (p *importedPack) addOverrides(source PackSource, folder string, serverOnly bool) {
+	for _, file := range source.List(folder) {
+		p.Overrides = append(p.Overrides, packOverride{
+			Source:     file,
+			Target:     strings.TrimPrefix(file, folder+"/"),
+			ServerOnly: serverOnly,
+		})
+	}
+}
+
+// ImportPack parses a Modrinth .mrpack, a CurseForge modpack or a packwiz
+// pack and creates a new session for it, with the links, the platform
+// and the versions filled in from its manifest and the overrides already in place
+func (c *Checker) ImportPack(source PackSource) (session *Session, result PackImport, err error) {
+
+	// Figure out the format of the pack by its manifest
+	var pack importedPack
+	if data, readErr := readPackFile(source, "modrinth.index.json"); readErr == nil {
+		pack, err = parseModrinthPack(source, data)
+	} else if data, readErr := readPackFile(source, "manifest.json"); readErr == nil {
+		pack, err = parseCurseForgePack(source, data)
+	} else if data, readErr := readPackFile(source, "pack.toml"); readErr == nil {
+		pack, err = parsePackwizPack(source, data)
+	} else {
+		err = fmt.Errorf("unknown modpack format")
+	}
+
+	if err != nil {
+		return
+	}
+
+	// Ensure the pack makes for a valid request
+	if err = pack.Request.Bind(nil); err != nil {
+		return
+	}
+
+	if err = c.CheckLinkCount(len(pack.Request.Links)); err != nil {
+		return
+	}
+
+	session = &Session{
+		Id:      uuid.New(),
+		Request: pack.Request,
+	}
+	session.Initialize()
+
+	// The overrides are relative to the root of the game, so we will place
+	// them straight into the package, where they count towards the quota
+	for _, override := range pack.Overrides {
+		quota := c.newDownloadQuota(session)
+		if err = copyPackOverride(source, override, session.DownloadsDirectory, quota); err != nil {
+			session.Delete()
+			session = nil
+			err = fmt.Errorf("unable to copy override %s: %s", override.Source, err)
+			return
+		}
+		session.OverridesSize += quota.size
+
+		if override.ServerOnly {
+			if session.ServerOverrides == nil {
+				session.ServerOverrides = make(map[string]bool)
+			}
+			session.ServerOverrides[path.Clean(override.Target)] = true
+		}
+	}
+
+	result = pack.PackImport
+	return
+}
+
+// copyPackOverride copies a single override file from the pack into a folder
+func copyPackOverride(source PackSource, override packOverride, folderPath string, quota *downloadQuota) error {
+	targetPath, err := utils.SafeJoin(folderPath, override.Target)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(path.Dir(targetPath), 0755); err != nil {
+		return err
+	}
+
+	in, err := source.Open(override.Source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(&quotaWriter{writer: out, quota: quota}, in)
+	return err
+}
+
+// modrinthPackEnv represents whether a file of a .mrpack
+// is required, optional or unsupported on each side
+type modrinthPackEnv struct {
+	Client string `json:"client"`
+	Server string `json:"server"`
+}
+
+// modrinthPackFile represents a single file of a .mrpack
+type modrinthPackFile struct {
+	Path      string            `json:"path"`
+	Hashes    map[string]string `json:"hashes"`
+	Env       *modrinthPackEnv  `json:"env,omitempty"`
+	Downloads []string          `json:"downloads"`
+	FileSize  int64             `json:"fileSize"`
+}
+
+// modrinthPackIndex represents the modrinth.index.json of a .mrpack
+type modrinthPackIndex struct {
+	FormatVersion int                `json:"formatVersion"`
+	Game          string             `json:"game"`
+	VersionId     string             `json:"versionId"`
+	Name          string             `json:"name"`
+	Files         []modrinthPackFile `json:"files"`
+	Dependencies  map[string]string  `json:"dependencies"`
+}
+
+// modrinthPackLoaders maps the dependencies of a .mrpack to our platforms
+var modrinthPackLoaders = map[string]platformType{
+	"forge":         Forge,
+	"neoforge":      NeoForge,
+	"fabric-loader": Fabric,
+	"quilt-loader":  Quilt,
+}
+
+// parseModrinthPack parses a Modrinth .mrpack
+func parseModrinthPack(source PackSource, data []byte) (pack importedPack, err error) {
+	var index modrinthPackIndex
+	if err = json.Unmarshal(data, &index); err != nil {
+		return
+	}
+
+	pack.Name = index.Name
+	pack.Format = "mrpack"
+	pack.Request.Name = index.Name
+	pack.Request.Links = make(map[string]string)
+	pack.Request.GameVersion = index.Dependencies["minecraft"]
+	for dependency, platform := range modrinthPackLoaders {
+		if platformVersion, ok := index.Dependencies[dependency]; ok {
+			pack.Request.Platform = platform
+			pack.Request.PlatformVersion = platformVersion
+		}
+	}
+
+	if pack.Request.Platform == "" {
+		err = fmt.Errorf("no supported loader found")
+		return
+	}
+
+	for _, file := range index.Files {
+
+		// We are building a server, so anything that can't run on one is left out
+		if file.Env != nil && file.Env.Server == "unsupported" {
+			pack.Skipped = append(pack.Skipped, file.Path)
+			continue
+		}
+
+		if len(file.Downloads) == 0 {
+			pack.Skipped = append(pack.Skipped, file.Path)
+			continue
+		}
+
+		// If it's hosted on Modrinth, we will link the exact version,
+		// otherwise we will just try to download it directly
+		link := file.Downloads[0]
+		if groups := utils.GetRegexGroups(modrinthCdnRegex, link); groups["project"] != "" {
+			link = fmt.Sprintf("https://modrinth.com/mod/%s/version/%s", groups["project"], groups["version"])
+		}
+
+		pack.addLink(file.Path, link)
+	}
+
+	pack.addOverrides(source, "overrides", false)
+	pack.addOverrides(source, "server-overrides", true)
+	return
+}
+
+// curseForgePackLoader represents a loader in the manifest.json of a CurseForge modpack
+type curseForgePackLoader struct {
+	Id      string `json:"id"`
+	Primary bool   `json:"primary"`
+}
+
+// curseForgePackFile represents a single file in the manifest.json of a CurseForge modpack
+type curseForgePackFile struct {
+	ProjectId int64 `json:"projectID"`
+	FileId    int64 `json:"fileID"`
+	Required  bool  `json:"required"`
+}
+
+// curseForgePackManifest represents the manifest.json of a CurseForge modpack
+type curseForgePackManifest struct {
+	Minecraft struct {
+		Version    string                 `json:"version"`
+		ModLoaders []curseForgePackLoader `json:"modLoaders"`
+	} `json:"minecraft"`
+	ManifestType    string               `json:"manifestType"`
+	ManifestVersion int                  `json:"manifestVersion"`
+	Name            string               `json:"name"`
+	Version         string               `json:"version"`
+	Author          string               `json:"author"`
+	Files           []curseForgePackFile `json:"files"`
+	Overrides       string               `json:"overrides"`
+}
+
+// parseCurseForgePack parses a CurseForge modpack's manifest.json
+func parseCurseForgePack(source PackSource, data []byte) (pack importedPack, err error) {
+	var manifest curseForgePackManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return
+	}
+
+	if manifest.ManifestType != "minecraftModpack" {
+		err = fmt.Errorf("unsupported manifest type: %s", manifest.ManifestType)
+		return
+	}
+
+	pack.Name = manifest.Name
+	pack.Format = "curseforge"
+	pack.Request.Name = manifest.Name
+	pack.Request.Links = make(map[string]string)
+	pack.Request.GameVersion = manifest.Minecraft.Version
+
+	// The loaders are in a <loader>-<version> format, such as forge-47.2.0
+	for _, loader := range manifest.Minecraft.ModLoaders {
+		if !loader.Primary && pack.Request.Platform != "" {
+			continue
+		}
+
+		parts := strings.SplitN(loader.Id, "-", 2)
+		if len(parts) == 2 {
+			pack.Request.Platform = platformType(parts[0])
+			pack.Request.PlatformVersion = parts[1]
+		}
+	}
+
+	// CurseForge modpacks only contain mods
+	for _, file := range manifest.Files {
+		link := fmt.Sprintf("https://www.curseforge.com/projects/%v/files/%v", file.ProjectId, file.FileId)
+		if !file.Required {
+			pack.Skipped = append(pack.Skipped, link)
+			continue
+		}
+
+		pack.Request.Links[uuid.New().String()] = link
+	}
+
+	if manifest.Overrides == "" {
+		manifest.Overrides = "overrides"
+	}
+	pack.addOverrides(source, manifest.Overrides, false)
+	return
+}
+
+// packwizIndexReference represents the reference to the index in a pack.toml
+type packwizIndexReference struct {
+	File       string `toml:"file"`
+	HashFormat string `toml:"hash-format,omitempty"`
+	Hash       string `toml:"hash,omitempty"`
+}
+
+// packwizPack represents the pack.toml of a packwiz pack
+type packwizPack struct {
+	Name       string                `toml:"name"`
+	PackFormat string                `toml:"pack-format,omitempty"`
+	Version    string                `toml:"version,omitempty"`
+	Index      packwizIndexReference `toml:"index"`
+	Versions   map[string]string     `toml:"versions"`
+}
+
+// packwizIndexFile represents a single file in the index.toml of a packwiz pack
+type packwizIndexFile struct {
+	File     string `toml:"file"`
+	Hash     string `toml:"hash,omitempty"`
+	Metafile bool   `toml:"metafile,omitempty"`
+}
+
+// packwizIndex represents the index.toml of a packwiz pack
+type packwizIndex struct {
+	HashFormat string             `toml:"hash-format,omitempty"`
+	Files      []packwizIndexFile `toml:"files"`
+}
+
+// packwizModrinthUpdate represents the Modrinth update section of a .pw.toml file
+type packwizModrinthUpdate struct {
+	ModId   string `toml:"mod-id"`
+	Version string `toml:"version"`
+}
+
+// packwizCurseForgeUpdate represents the CurseForge update section of a .pw.toml file
+type packwizCurseForgeUpdate struct {
+	FileId    int64 `toml:"file-id"`
+	ProjectId int64 `toml:"project-id"`
+}
+
+// packwizMetafile represents a .pw.toml file of a packwiz pack
+type packwizMetafile struct {
+	Name     string `toml:"name"`
+	FileName string `toml:"filename"`
+	Side     string `toml:"side,omitempty"`
+	Download struct {
+		Url        string `toml:"url,omitempty"`
+		HashFormat string `toml:"hash-format,omitempty"`
+		Hash       string `toml:"hash,omitempty"`
+		Mode       string `toml:"mode,omitempty"`
+	} `toml:"download"`
+	Update struct {
+		Modrinth   *packwizModrinthUpdate   `toml:"modrinth,omitempty"`
+		CurseForge *packwizCurseForgeUpdate `toml:"curseforge,omitempty"`
+	} `toml:"update,omitempty"`
+}
+
+// packwizLoaders maps the versions of a pack.toml to our platforms
+var packwizLoaders = map[string]platformType{
+	"forge":    Forge,
+	"neoforge": NeoForge,
+	"fabric":   Fabric,
+	"quilt":    Quilt,
+}
+
+// parsePackwizPack parses a packwiz pack.toml, its index and all of its metafiles
+func parsePackwizPack(source PackSource, data []byte) (pack importedPack, err error) {
+	var rawPack packwizPack
+	if err = toml.Unmarshal(data, &rawPack); err != nil {
+		return
+	}
+
+	pack.Name = rawPack.Name
+	pack.Format = "packwiz"
+	pack.Request.Name = rawPack.Name
+	pack.Request.Links = make(map[string]string)
+	pack.Request.GameVersion = rawPack.Versions["minecraft"]
+	for loader, platform := range packwizLoaders {
+		if platformVersion, ok := rawPack.Versions[loader]; ok {
+			pack.Request.Platform = platform
+			pack.Request.PlatformVersion = platformVersion
+		}
+	}
+
+	if pack.Request.Platform == "" {
+		err = fmt.Errorf("no supported loader found")
+		return
+	}
+
+	if rawPack.Index.File == "" {
+		err = fmt.Errorf("no index file found")
+		return
+	}
+
+	indexData, err := readPackFile(source, rawPack.Index.File)
+	if err != nil {
+		return
+	}
+
+	var index packwizIndex
+	if err = toml.Unmarshal(indexData, &index); err != nil {
+		return
+	}
+
+	// The files in the index are relative to the index itself
+	indexFolder := path.Dir(rawPack.Index.File)
+	for _, file := range index.Files {
+		filePath := path.Join(indexFolder, file.File)
+
+		// Anything that isn't a metafile is just copied over
+		if !file.Metafile && !strings.HasSuffix(file.File, ".pw.toml") {
+			pack.Overrides = append(pack.Overrides, packOverride{Source: filePath, Target: file.File})
+			continue
+		}
+
+		var metafileData []byte
+		if metafileData, err = readPackFile(source, filePath); err != nil {
+			return
+		}
+
+		var metafile packwizMetafile
+		if err = toml.Unmarshal(metafileData, &metafile); err != nil {
+			err = fmt.Errorf("unable to parse %s: %s", file.File, err)
+			return
+		}
+
+		// We are building a server, so anything client-only is left out
+		targetPath := path.Join(path.Dir(file.File), metafile.FileName)
+		if metafile.Side == "client" {
+			pack.Skipped = append(pack.Skipped, targetPath)
+			continue
+		}
+
+		// Prefer the exact version from the provider and fall back to the direct link
+		link := metafile.Download.Url
+		if metafile.Update.Modrinth != nil {
+			link = fmt.Sprintf("https://modrinth.com/mod/%s/version/%s", metafile.Update.Modrinth.ModId, metafile.Update.Modrinth.Version)
+		} else if metafile.Update.CurseForge != nil {
+			link = fmt.Sprintf("https://www.curseforge.com/projects/%v/files/%v", metafile.Update.CurseForge.ProjectId, metafile.Update.CurseForge.FileId)
+		}
+
+		if link == "" {
+			pack.Skipped = append(pack.Skipped, targetPath)
+			continue
+		}
+
+		pack.addLink(targetPath, link)
+	}
+
+	return
+}
@@ -0,0 +1,147 @@
+package checker
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"sync"
+)
+
+// DownloadPool runs the downloads of every session with a limited
+// number of workers overall and for each session, taking turns
+// between the sessions, so a large one does not hold up the others
+type DownloadPool struct {
+	lock           *sync.Mutex
+	workers        int
+	sessionWorkers int
+	active         int
+	queues         map[uuid.UUID]*poolQueue
+	order          []uuid.UUID
+	next           int
+}
+
+// poolQueue represents the pending jobs of a single session
+type poolQueue struct {
+	jobs   []func()
+	active int
+}
+
+// NewDownloadPool creates a new pool with the given limits
+func NewDownloadPool(workers, sessionWorkers int) *DownloadPool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	if sessionWorkers < 1 || sessionWorkers > workers {
+		sessionWorkers = workers
+	}
+
+	return &DownloadPool{
+		lock:           &sync.Mutex{},
+		workers:        workers,
+		sessionWorkers: sessionWorkers,
+		queues:         make(map[uuid.UUID]*poolQueue),
+	}
+}
+
+// Run runs each job for the session on the pool and waits for all of them to finish
+// Without a pool, the jobs are simply run one after the other
+// Once the context is cancelled, the jobs that have not started yet are skipped
+func (p *DownloadPool) Run(ctx context.Context, sessionId uuid.UUID, jobs ...func()) {
+	if p == nil {
+		for _, job := range jobs {
+			if ctx.Err() == nil {
+				job()
+			}
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(jobs))
+
+	p.lock.Lock()
+	queue, ok := p.queues[sessionId]
+	if !ok {
+		queue = &poolQueue{}
+		p.queues[sessionId] = queue
+		p.order = append(p.order, sessionId)
+	}
+
+	for _, job := range jobs {
+		job := job
+		queue.jobs = append(queue.jobs, func() {
+			defer wg.Done()
+			if ctx.Err() == nil {
+				job()
+			}
+		})
+	}
+	p.lock.Unlock()
+
+	p.schedule()
+	wg.Wait()
+}
+
+// schedule starts as many of the pending jobs as the limits allow,
+// going through the sessions in turns
+func (p *DownloadPool) schedule() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for p.active < p.workers {
+		sessionId, queue := p.getNextQueue()
+		if queue == nil {
+			return
+		}
+
+		job := queue.jobs[0]
+		queue.jobs = queue.jobs[1:]
+		queue.active++
+		p.active++
+
+		go func(sessionId uuid.UUID, queue *poolQueue, job func()) {
+			job()
+
+			p.lock.Lock()
+			queue.active--
+			p.active--
+			p.removeQueue(sessionId, queue)
+			p.lock.Unlock()
+
+			p.schedule()
+		}(sessionId, queue, job)
+	}
+}
+
+// getNextQueue returns the next session in turn that has pending jobs
+// and has not reached its limit yet. It must be called with the lock held
+func (p *DownloadPool) getNextQueue() (uuid.UUID, *poolQueue) {
+	for i := 0; i < len(p.order); i++ {
+		index := (p.next + i) % len(p.order)
+		queue := p.queues[p.order[index]]
+		if len(queue.jobs) > 0 && queue.active < p.sessionWorkers {
+			p.next = index + 1
+			return p.order[index], queue
+		}
+	}
+	return uuid.Nil, nil
+}
+
+// removeQueue forgets about a session once it has no more jobs
+// It must be called with the lock held
+func (p *DownloadPool) removeQueue(sessionId uuid.UUID, queue *poolQueue) {
+	if len(queue.jobs) > 0 || queue.active > 0 || p.queues[sessionId] != queue {
+		return
+	}
+
+	delete(p.queues, sessionId)
+	for i, id := range p.order {
+		if id == sessionId {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			if p.next > i {
+				p.next--
+			}
+			break
+		}
+	}
+}
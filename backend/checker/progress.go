@@ -0,0 +1,147 @@
+package checker
+
+import (
+	"geri.dev/pack-builder/web/sockets"
+	"github.com/google/uuid"
+	"io"
+	"sync"
+	"time"
+)
+
+// How often the progress of a download is sent to the sockets at most
+const progressInterval = 500 * time.Millisecond
+
+// Progress represents how far along a stage of a session is
+type Progress struct {
+	Done  int   `json:"done"`
+	Total int   `json:"total"`
+	ETA   int64 `json:"eta"` // The estimated remaining time in seconds
+}
+
+// newProgress creates a new progress and estimates the remaining
+// time based on how long the completed steps took
+func newProgress(done, total int, started time.Time) Progress {
+	progress := Progress{Done: done, Total: total}
+	if done > 0 && done < total {
+		perStep := time.Since(started) / time.Duration(done)
+		progress.ETA = int64((perStep * time.Duration(total-done)).Seconds())
+	}
+	return progress
+}
+
+// DownloadProgress represents how far along a single download is
+// The server JAR is reported with an empty ID
+type DownloadProgress struct {
+	Id       uuid.UUID `json:"id"`
+	Received int64     `json:"received"`
+	Total    int64     `json:"total"` // The size of the file in bytes, or 0 if it's unknown
+	Speed    int64     `json:"speed"` // The average speed in bytes per second
+
+	started  time.Time
+	reported time.Time
+}
+
+// SessionProgress represents how far along all the downloads of a session are
+// The total only includes the sizes we know of so far
+type SessionProgress struct {
+	Received int64 `json:"received"`
+	Total    int64 `json:"total"`
+	Speed    int64 `json:"speed"`
+	Done     int   `json:"done"`
+	Files    int   `json:"files"`
+}
+
+// downloadTracker keeps track of the downloads of a session and
+// broadcasts their progress, at most once every progressInterval
+type downloadTracker struct {
+	session   *Session
+	lock      sync.Mutex
+	started   time.Time
+	reported  time.Time
+	files     int
+	done      int
+	downloads map[uuid.UUID]*DownloadProgress
+}
+
+// newDownloadTracker creates a new tracker for the passed number of files
+func newDownloadTracker(session *Session, files int) *downloadTracker {
+	return &downloadTracker{
+		session:   session,
+		started:   time.Now(),
+		files:     files,
+		downloads: make(map[uuid.UUID]*DownloadProgress),
+	}
+}
+
+// getSpeed returns the average speed in bytes per second
+func getSpeed(received int64, elapsed time.Duration) int64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return int64(float64(received) / elapsed.Seconds())
+}
+
+// update records how much of a download has been received so far
+func (t *downloadTracker) update(id uuid.UUID, received, total int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	progress, ok := t.downloads[id]
+	if !ok {
+		progress = &DownloadProgress{Id: id, started: now}
+		t.downloads[id] = progress
+	}
+
+	progress.Received = received
+	progress.Total = total
+	if now.Sub(progress.reported) >= progressInterval {
+		progress.reported = now
+		progress.Speed = getSpeed(progress.Received, now.Sub(progress.started))
+		_ = t.session.BroadcastToSockets(sockets.DownloadProgress, progress)
+	}
+
+	t.broadcastSession(now, false)
+}
+
+// finish marks one of the files as done, whether it was successful or not
+func (t *downloadTracker) finish() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.done++
+	t.broadcastSession(time.Now(), true)
+}
+
+// broadcastSession sends the progress of the whole session to the sockets
+// It must be called with the lock held
+func (t *downloadTracker) broadcastSession(now time.Time, force bool) {
+	if !force && now.Sub(t.reported) < progressInterval {
+		return
+	}
+	t.reported = now
+
+	progress := SessionProgress{Done: t.done, Files: t.files}
+	for _, download := range t.downloads {
+		progress.Received += download.Received
+		progress.Total += download.Total
+	}
+	progress.Speed = getSpeed(progress.Received, now.Sub(t.started))
+
+	_ = t.session.BroadcastToSockets(sockets.SessionProgress, progress)
+}
+
+// progressReader reports how much has been read so far after each read
+type progressReader struct {
+	reader   io.Reader
+	received int64
+	total    int64
+	report   func(received, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.received += int64(n)
+	r.report(r.received, r.total)
+	return
+}
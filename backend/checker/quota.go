@@ -0,0 +1,139 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// quotaError is returned when a session goes over one of its quotas
+type quotaError struct {
+	message string
+}
+
+func (e *quotaError) Error() string {
+	return e.message
+}
+
+// isQuotaExceeded returns whether an error was caused by one of the quotas
+func isQuotaExceeded(err error) bool {
+	var quota *quotaError
+	return errors.As(err, &quota)
+}
+
+// CheckLinkCount returns an error if a session would have more links than it is allowed to
+func (c *Checker) CheckLinkCount(count int) error {
+	if c.Quotas.MaxLinks > 0 && count > c.Quotas.MaxLinks {
+		return &quotaError{fmt.Sprintf("too many links, a session can have at most %d", c.Quotas.MaxLinks)}
+	}
+	return nil
+}
+
+// downloadQuota keeps track of how much a single file adds to the
+// usage of its session, so neither of them go over their quota
+type downloadQuota struct {
+	session        *Session
+	maxFileSize    int64
+	maxSessionSize int64
+
+	// The size of the file that was counted towards the session so far
+	size int64
+}
+
+// newDownloadQuota starts keeping track of a new file for a session
+func (c *Checker) newDownloadQuota(session *Session) *downloadQuota {
+	return &downloadQuota{
+		session:        session,
+		maxFileSize:    c.Quotas.GetMaxFileSize(),
+		maxSessionSize: c.Quotas.GetMaxSessionSize(),
+	}
+}
+
+// check returns an error if the file would go over one of the quotas with the passed size
+func (q *downloadQuota) check(size int64) error {
+	return q.update(size, false)
+}
+
+// setSize counts the new size of the file towards the session,
+// unless it would go over one of the quotas
+func (q *downloadQuota) setSize(size int64) error {
+	return q.update(size, true)
+}
+
+// release stops counting the file towards the session, once it was removed
+func (q *downloadQuota) release() {
+	_ = q.update(0, true)
+}
+
+func (q *downloadQuota) update(size int64, commit bool) error {
+	if q.maxFileSize > 0 && size > q.maxFileSize {
+		return &quotaError{fmt.Sprintf("the file is larger than the limit of %d MB", q.maxFileSize/(1024*1024))}
+	}
+
+	q.session.usageLock.Lock()
+	defer q.session.usageLock.Unlock()
+
+	usage := q.session.usage - q.size + size
+	if q.maxSessionSize > 0 && size > q.size && usage > q.maxSessionSize {
+		return &quotaError{fmt.Sprintf("the session would download more than the limit of %d MB", q.maxSessionSize/(1024*1024))}
+	}
+
+	if commit {
+		q.session.usage = usage
+		q.size = size
+	}
+	return nil
+}
+
+// quotaWriter counts everything written to a file towards its quota,
+// and stops as soon as the file goes over it
+type quotaWriter struct {
+	writer io.Writer
+	quota  *downloadQuota
+	size   int64
+}
+
+func (w *quotaWriter) Write(p []byte) (n int, err error) {
+	if err = w.quota.setSize(w.size + int64(len(p))); err != nil {
+		return
+	}
+
+	n, err = w.writer.Write(p)
+	w.size += int64(n)
+	return
+}
+
+// quotaReadCloser stops reading with an error once more than the maximum size was read,
+// so a file can not be larger than it claims to be, or than the archive it is in suggests
+type quotaReadCloser struct {
+	reader    io.ReadCloser
+	maxSize   int64
+	remaining int64
+	cancel    func()
+}
+
+// newQuotaReadCloser limits a reader to maxSize bytes, unless it's 0
+// The cancel function is called once the reader is closed, if it's passed
+func newQuotaReadCloser(reader io.ReadCloser, maxSize int64, cancel func()) *quotaReadCloser {
+	return &quotaReadCloser{reader: reader, maxSize: maxSize, remaining: maxSize, cancel: cancel}
+}
+
+func (r *quotaReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	if r.maxSize <= 0 {
+		return
+	}
+
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		err = &quotaError{fmt.Sprintf("the file is larger than the limit of %d MB", r.maxSize/(1024*1024))}
+	}
+	return
+}
+
+func (r *quotaReadCloser) Close() error {
+	if r.cancel != nil {
+		defer r.cancel()
+	}
+	return r.reader.Close()
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+// CompatibleLoader represents a loader whose builds a
+// platform is able to load, optionally only on some game versions
+type CompatibleLoader struct {
+	Loader       string
+	GameVersions []string `yaml:"game-versions"`
+}
+
+// UnmarshalYAML allows loaders to be written as a plain string
+// when they are not restricted to specific game versions
+func (cl *CompatibleLoader) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		cl.Loader = value.Value
+		return nil
+	}
+
+	type rawLoader CompatibleLoader
+	return value.Decode((*rawLoader)(cl))
+}
+
+// Compatibility maps each platform and mode to the loaders whose
+// builds it is able to load, in the order they are preferred
+type Compatibility map[string]map[string][]CompatibleLoader
+
+// loaders is a shorthand to create loaders that are not restricted to specific game versions
+func loaders(names ...string) (result []CompatibleLoader) {
+	for _, name := range names {
+		result = append(result, CompatibleLoader{Loader: name})
+	}
+	return
+}
+
+// defaultCompatibility returns the compatibility used
+// for any platforms that are not in the config
+func defaultCompatibility() Compatibility {
+	return Compatibility{
+		// Plenty of Bukkit plugins are only tagged as Paper on Modrinth and Hangar
+		"spigot": {"plugins": loaders("spigot", "bukkit", "paper")},
+		"paper":  {"plugins": loaders("paper", "spigot", "bukkit")},
+		"purpur": {"plugins": loaders("purpur", "paper", "spigot", "bukkit")},
+
+		// Hangar does not differentiate Folia from Paper, so we will
+		// accept those and check the plugin.yml once it is downloaded
+		"folia":  {"plugins": loaders("folia", "paper")},
+		"sponge": {"plugins": loaders("sponge")},
+
+		"velocity":   {"plugins": loaders("velocity")},
+		"bungeecord": {"plugins": loaders("bungeecord", "waterfall")},
+		"waterfall":  {"plugins": loaders("waterfall", "bungeecord")},
+
+		"mohist": {
+			"mods":    loaders("forge"),
+			"plugins": loaders("bukkit", "spigot", "paper"),
+		},
+		"arclight": {
+			"mods":    loaders("forge", "neoforge"),
+			"plugins": loaders("bukkit", "spigot", "paper"),
+		},
+
+		"fabric": {"mods": loaders("fabric")},
+		"quilt":  {"mods": loaders("quilt", "fabric")},
+		"forge":  {"mods": loaders("forge")},
+
+		// The first NeoForge release was still able to load Forge mods
+		"neoforge": {"mods": []CompatibleLoader{
+			{Loader: "neoforge"},
+			{Loader: "forge", GameVersions: []string{"1.20.1"}},
+		}},
+	}
+}
+
+// GetLoaders returns the loaders whose builds the platform is able to
+// load as a specific mode on a game version, in the order they are preferred
+func (c Compatibility) GetLoaders(platform, mode, gameVersion string) (result []string) {
+	for _, loader := range c[platform][mode] {
+		if len(loader.GameVersions) > 0 {
+			supported := false
+			for _, supportedVersion := range loader.GameVersions {
+				if supportedVersion == gameVersion {
+					supported = true
+					break
+				}
+			}
+
+			if !supported {
+				continue
+			}
+		}
+
+		result = append(result, strings.ToLower(loader.Loader))
+	}
+
+	return
+}
@@ -3,6 +3,7 @@ package config
 import (
 	_ "embed"
 	"fmt"
+	"geri.dev/pack-builder/utils"
 	"gopkg.in/yaml.v3"
 	"os"
 	"path/filepath"
@@ -21,6 +22,7 @@ type credentials struct {
 	GitHub     token
 	CurseForge token
 	Modrinth   token
+	Ore        token
 }
 
 type DomainProvider string
@@ -39,9 +41,29 @@ type web struct {
 	PublicUrl string `yaml:"public-url"`
 }
 
+type libraries struct {
+	Repositories []string
+}
+
+type preliminary struct {
+	Workers int
+}
+
+type downloads struct {
+	Workers        int
+	SessionWorkers int `yaml:"session-workers"`
+	Retries        int
+}
+
 type Config struct {
-	Web         web
-	Credentials credentials
+	Web           web
+	Credentials   credentials
+	Compatibility Compatibility
+	Libraries     libraries
+	Preliminary   preliminary
+	Downloads     downloads
+	Outbound      utils.OutboundPolicy
+	Quotas        Quotas
 }
 
 // FormatEndpoint Removes trailing slashes
@@ -78,7 +100,26 @@ func LoadConfig() (cfg Config, err error) {
 	}
 
 	// We can set some default values here
-	cfg = Config{}
+	cfg = Config{
+		Compatibility: defaultCompatibility(),
+		Libraries: libraries{
+			Repositories: []string{"https://repo.maven.apache.org/maven2"},
+		},
+		Preliminary: preliminary{
+			Workers: 8,
+		},
+		Downloads: downloads{
+			Workers:        16,
+			SessionWorkers: 5,
+			Retries:        3,
+		},
+		Quotas: Quotas{
+			MaxLinks:       250,
+			MaxFileSize:    256,
+			MaxSessionSize: 4096,
+			RequestTimeout: 300,
+		},
+	}
 
 	// Parse YAML
 	err = yaml.Unmarshal(data, &cfg)
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"context"
+	"time"
+)
+
+const megabyte = 1024 * 1024
+
+// Quotas limits how much each session is allowed to use,
+// where a value of 0 means there is no limit
+type Quotas struct {
+
+	// How many links a session can have
+	MaxLinks int `yaml:"max-links"`
+
+	// How large a single downloaded file can be, in megabytes
+	MaxFileSize int64 `yaml:"max-file-size"`
+
+	// How much a session can download in total, in megabytes
+	MaxSessionSize int64 `yaml:"max-session-size"`
+
+	// How long a single download or API request can take, in seconds
+	RequestTimeout int `yaml:"request-timeout"`
+}
+
+// GetMaxFileSize returns the largest size of a single file in bytes
+func (q Quotas) GetMaxFileSize() int64 {
+	return q.MaxFileSize * megabyte
+}
+
+// GetMaxSessionSize returns the most a session can download in bytes
+func (q Quotas) GetMaxSessionSize() int64 {
+	return q.MaxSessionSize * megabyte
+}
+
+// GetRequestTimeout returns how long a single request can take
+func (q Quotas) GetRequestTimeout() time.Duration {
+	return time.Duration(q.RequestTimeout) * time.Second
+}
+
+// RequestContext derives the context of a single request from the passed one,
+// which is cancelled once the request takes longer than it's allowed to
+func (q Quotas) RequestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := q.GetRequestTimeout(); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
@@ -3,6 +3,7 @@ module geri.dev/pack-builder
 go 1.19
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/go-chi/chi/v5 v5.0.11
 	github.com/go-chi/render v1.0.3
 	github.com/google/go-github v17.0.0+incompatible
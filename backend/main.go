@@ -46,6 +46,7 @@ func main() {
 			router.Route("/sessions", func(router chi.Router) {
 				router.Get("/", backend.TemporaryHandler)
 				router.Post("/", backend.CreationHandler)
+				router.Post("/import", backend.ImportHandler)
 
 				router.Route("/{id}", func(router chi.Router) {
 					router.HandleFunc("/socket", backend.SocketHandler)
@@ -53,6 +54,7 @@ func main() {
 					router.Get("/download/{packageId}", backend.DownloadHandler)
 					router.Post("/preliminary", backend.PreliminaryHandler)
 					router.Post("/process", backend.ProcessHandler)
+					router.Post("/cancel", backend.CancelHandler)
 					router.Delete("/", backend.DeletionHandler)
 				})
 			})
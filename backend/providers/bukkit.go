@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"geri.dev/pack-builder/config"
 	"net/http"
 )
@@ -22,7 +23,7 @@ func (bp *BukkitProvider) GetPluginProviderName() string {
 	return "bukkit"
 }
 
-func (bp *BukkitProvider) GetPluginInfoFromLink(link string) (info PluginInfo, err error) {
+func (bp *BukkitProvider) GetPluginInfoFromLink(ctx context.Context, link string) (info PluginInfo, err error) {
 
 	// Todo (notgeri):  Bukkit scraper
 	/*
@@ -37,7 +38,7 @@ func (bp *BukkitProvider) GetPluginInfoFromLink(link string) (info PluginInfo, e
 	return
 }
 
-func (bp *BukkitProvider) GetPluginInfoFromProjectName(name string) (info PluginInfo, err error) {
+func (bp *BukkitProvider) GetPluginInfoFromProjectName(ctx context.Context, name string) (info PluginInfo, err error) {
 	// same as with url, just /projects/<id> or /projects/<name>
 	return
 }
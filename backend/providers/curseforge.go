@@ -0,0 +1,272 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"geri.dev/pack-builder/config"
+	"geri.dev/pack-builder/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var curseForgeBaseEndpoint = "https://api.curseforge.com/v1"
+var curseForgeUserAccessibleEndpoint = "https://www.curseforge.com"
+var curseForgeLinkRegex = regexp.MustCompile("https://(?:www\\.)?curseforge\\.com/(?:minecraft/[^/]+/(?P<slug>[^/?#]+)|projects/(?P<id>[0-9]+))(?:/files/(?P<file>[0-9]+))?")
+
+// The CurseForge ID of Minecraft and the classes of Bukkit plugins, data packs and resource packs
+const curseForgeGameId = 432
+const curseForgeBukkitClassId = 5
+const curseForgeDatapackClassId = 6945
+const curseForgeResourcePackClassId = 12
+
+// curseForgeLoaders maps the loaders CurseForge lists
+// among the game versions to the names we use
+var curseForgeLoaders = map[string]string{
+	"Forge":    "forge",
+	"NeoForge": "neoforge",
+	"Fabric":   "fabric",
+	"Quilt":    "quilt",
+}
+
+type CurseForgeProvider struct {
+	cfg *config.Config
+	c   *http.Client
+}
+
+func NewCurseForgeProvider(cfg *config.Config) CurseForgeProvider {
+	return CurseForgeProvider{
+		cfg: cfg,
+		c:   &http.Client{},
+	}
+}
+
+// GetPluginProviderName returns the ID for the provider
+func (cp *CurseForgeProvider) GetPluginProviderName() string {
+	return "curseforge"
+}
+
+// makeRequest sends a new CurseForge API request
+func (cp *CurseForgeProvider) makeRequest(ctx context.Context, method, url string, result interface{}) error {
+	ctx, cancel := cp.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", curseForgeBaseEndpoint, url), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("x-api-key", cp.cfg.Credentials.CurseForge.Token)
+	req.Header.Set("User-Agent", cp.cfg.Credentials.UserAgent)
+
+	resp, err := cp.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get resource, status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+type curseForgePluginInfo struct {
+	Id      int64  `json:"id"`
+	Name    string `json:"name"`
+	Slug    string `json:"slug"`
+	Summary string `json:"summary"`
+	ClassId int64  `json:"classId"`
+	Links   struct {
+		WebsiteUrl string `json:"websiteUrl"`
+	} `json:"links"`
+	Authors []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Logo *struct {
+		Url string `json:"url"`
+	} `json:"logo"`
+}
+
+type curseForgePluginFile struct {
+	Id           int64    `json:"id"`
+	FileName     string   `json:"fileName"`
+	DownloadUrl  *string  `json:"downloadUrl"`
+	FileLength   int64    `json:"fileLength"`
+	GameVersions []string `json:"gameVersions"`
+}
+
+// ToVersion converts a CurseForge file into a generic version.
+// CurseForge mixes the loaders and the game versions in one list,
+// so we will have to split those up
+func (f curseForgePluginFile) ToVersion(info curseForgePluginInfo) Version {
+	platforms := make([]string, 0)
+	gameVersions := make([]string, 0)
+	for _, gameVersion := range f.GameVersions {
+		if loader, ok := curseForgeLoaders[gameVersion]; ok {
+			platforms = append(platforms, loader)
+		} else if strings.HasPrefix(gameVersion, "1.") {
+			gameVersions = append(gameVersions, gameVersion)
+		}
+	}
+
+	// Bukkit plugins and packs do not list any loaders and
+	// older mods are from before there were any other than Forge
+	if len(platforms) == 0 {
+		switch info.ClassId {
+		case curseForgeBukkitClassId:
+			platforms = nil
+		case curseForgeDatapackClassId:
+			platforms = append(platforms, DatapackLoader)
+		case curseForgeResourcePackClassId:
+			platforms = append(platforms, ResourcePackLoader)
+		default:
+			platforms = append(platforms, "forge")
+		}
+	}
+
+	// Some authors do not allow third-party downloads, in that case
+	// there is nothing for us to download, and they have to do it manually
+	fileUrl := ""
+	if f.DownloadUrl != nil {
+		fileUrl = *f.DownloadUrl
+	}
+
+	return Version{
+		Id:           fmt.Sprintf("%v", f.Id),
+		Link:         fmt.Sprintf("%s/files/%v", info.Links.WebsiteUrl, f.Id),
+		IsExternal:   f.DownloadUrl == nil,
+		URL:          fileUrl,
+		Platforms:    platforms,
+		GameVersions: gameVersions,
+		Size:         f.FileLength,
+		FileName:     f.FileName,
+	}
+}
+
+// ToPluginInfo converts a CurseForge project and its files into a generic PluginInfo struct
+func (i curseForgePluginInfo) ToPluginInfo(files []curseForgePluginFile) PluginInfo {
+	versions := make([]Version, 0)
+	for _, file := range files {
+		versions = append(versions, file.ToVersion(i))
+	}
+
+	authors := make([]string, 0)
+	for _, author := range i.Authors {
+		authors = append(authors, author.Name)
+	}
+
+	iconLink := ""
+	if i.Logo != nil {
+		iconLink = i.Logo.Url
+	}
+
+	return PluginInfo{
+		Type:         CurseForge,
+		Id:           fmt.Sprintf("%v", i.Id),
+		Link:         i.Links.WebsiteUrl,
+		Name:         i.Name,
+		Description:  i.Summary,
+		Contributors: strings.Join(authors, ", "),
+		Versions:     versions,
+		IconLink:     iconLink,
+	}
+}
+
+// getPluginInfo gets the details and the files of a project from the CurseForge API
+// If a file ID is passed, only that specific file will be returned as a version
+func (cp *CurseForgeProvider) getPluginInfo(ctx context.Context, rawInfo curseForgePluginInfo, fileId string) (info PluginInfo, err error) {
+	var files []curseForgePluginFile
+	if fileId != "" {
+		var rawFile struct {
+			Data curseForgePluginFile `json:"data"`
+		}
+		if err = cp.makeRequest(ctx, "GET", fmt.Sprintf("/mods/%v/files/%s", rawInfo.Id, fileId), &rawFile); err != nil {
+			return
+		}
+		files = append(files, rawFile.Data)
+	} else {
+		var rawFiles struct {
+			Data []curseForgePluginFile `json:"data"`
+		}
+		if err = cp.makeRequest(ctx, "GET", fmt.Sprintf("/mods/%v/files?pageSize=50", rawInfo.Id), &rawFiles); err != nil {
+			return
+		}
+		files = rawFiles.Data
+	}
+
+	info = rawInfo.ToPluginInfo(files)
+	return
+}
+
+// findBySlug looks up a Minecraft project by its slug
+func (cp *CurseForgeProvider) findBySlug(ctx context.Context, slug string) (rawInfo curseForgePluginInfo, err error) {
+	var results struct {
+		Data []curseForgePluginInfo `json:"data"`
+	}
+	if err = cp.makeRequest(ctx, "GET", fmt.Sprintf("/mods/search?gameId=%v&slug=%s", curseForgeGameId, url.QueryEscape(slug)), &results); err != nil {
+		return
+	}
+
+	for _, result := range results.Data {
+		if strings.EqualFold(result.Slug, slug) {
+			rawInfo = result
+			return
+		}
+	}
+
+	err = fmt.Errorf("no project found with this slug")
+	return
+}
+
+// GetPluginInfoFromLink attempts to parse the project slug or ID of a link
+// and get its details from the CurseForge API. If the link points to
+// a specific file, only that file will be returned as a version
+func (cp *CurseForgeProvider) GetPluginInfoFromLink(ctx context.Context, link string) (info PluginInfo, err error) {
+
+	// Parse the project
+	groups := utils.GetRegexGroups(curseForgeLinkRegex, link)
+	slug := groups["slug"]
+	id := groups["id"]
+	if slug == "" && id == "" {
+		err = fmt.Errorf("unable to parse CurseForge project")
+		return
+	}
+
+	// Get the base project information
+	var rawInfo curseForgePluginInfo
+	if id != "" {
+		var rawProject struct {
+			Data curseForgePluginInfo `json:"data"`
+		}
+		if err = cp.makeRequest(ctx, "GET", fmt.Sprintf("/mods/%s", id), &rawProject); err != nil {
+			return
+		}
+		rawInfo = rawProject.Data
+	} else if rawInfo, err = cp.findBySlug(ctx, slug); err != nil {
+		return
+	}
+
+	return cp.getPluginInfo(ctx, rawInfo, groups["file"])
+}
+
+// GetPluginInfoFromProjectName attempts to get the details of a project
+// from its name. CurseForge slugs are unique and usually match the name
+// of the project, so we will look it up as one
+func (cp *CurseForgeProvider) GetPluginInfoFromProjectName(ctx context.Context, name string) (info PluginInfo, err error) {
+	rawInfo, err := cp.findBySlug(ctx, strings.ReplaceAll(strings.ToLower(name), " ", "-"))
+	if err != nil {
+		return
+	}
+
+	return cp.getPluginInfo(ctx, rawInfo, "")
+}
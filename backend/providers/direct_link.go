@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"errors"
 	"geri.dev/pack-builder/config"
 	"io"
@@ -13,9 +14,11 @@ type DirectDownloadProvider struct {
 }
 
 func NewDirectDownloadProvider(cfg *config.Config) DirectDownloadProvider {
+	client := cfg.Outbound.Client()
+	client.Timeout = cfg.Quotas.GetRequestTimeout()
 	return DirectDownloadProvider{
 		cfg: cfg,
-		c:   &http.Client{},
+		c:   client,
 	}
 }
 
@@ -26,10 +29,15 @@ func (ddp *DirectDownloadProvider) GetExternalProviderName() string {
 
 // GetJARDownloadLinksFromLink attempts to verify that an
 // external link is a direct download link to a JAR
-func (ddp *DirectDownloadProvider) GetJARDownloadLinksFromLink(link string) (downloadLinks []string, err error) {
+func (ddp *DirectDownloadProvider) GetJARDownloadLinksFromLink(ctx context.Context, link string) (downloadLinks []string, err error) {
 
 	// First, try with a HEAD request
-	headResp, err := ddp.c.Head(link)
+	headReq, err := http.NewRequestWithContext(ctx, "HEAD", link, nil)
+	if err != nil {
+		return
+	}
+
+	headResp, err := ddp.c.Do(headReq)
 	if err != nil {
 		return
 	}
@@ -43,7 +51,12 @@ func (ddp *DirectDownloadProvider) GetJARDownloadLinksFromLink(link string) (dow
 	}
 
 	// If HEAD request fails, fall back to a GET request
-	getResp, err := ddp.c.Get(link)
+	getReq, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return
+	}
+
+	getResp, err := ddp.c.Do(getReq)
 	if err != nil {
 		return
 	}
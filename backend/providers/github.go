@@ -15,7 +15,6 @@ var githubRegex = regexp.MustCompile("https://github\\.com/(?P<owner>.*)/(?P<rep
 
 type GitHubProvider struct {
 	cfg    *config.Config
-	ctx    context.Context
 	client *github.Client
 }
 
@@ -25,7 +24,7 @@ func NewGitHubProvider(cfg *config.Config) GitHubProvider {
 	ctx := context.Background()
 	tc := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(tc)
-	return GitHubProvider{cfg, ctx, client}
+	return GitHubProvider{cfg, client}
 }
 
 // GetExternalProviderName returns the ID for the external provider
@@ -34,7 +33,7 @@ func (gp *GitHubProvider) GetExternalProviderName() string {
 }
 
 // GetReleaseFromLink attempts to get all the release from a repository url
-func (ghp *GitHubProvider) GetReleaseFromLink(link string) (release *github.RepositoryRelease, err error) {
+func (ghp *GitHubProvider) GetReleaseFromLink(ctx context.Context, link string) (release *github.RepositoryRelease, err error) {
 
 	// Parse the owner and repository from the link
 	groups := utils.GetRegexGroups(githubRegex, link)
@@ -46,8 +45,12 @@ func (ghp *GitHubProvider) GetReleaseFromLink(link string) (release *github.Repo
 		return
 	}
 
+	// The lookup can take a few requests, which all have to fit into the time of a single one
+	ctx, cancel := ghp.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
 	// Attempt to get the repository
-	repository, _, err := ghp.client.Repositories.Get(ghp.ctx, owner, repo)
+	repository, _, err := ghp.client.Repositories.Get(ctx, owner, repo)
 	if err != nil {
 		return
 	}
@@ -59,21 +62,21 @@ func (ghp *GitHubProvider) GetReleaseFromLink(link string) (release *github.Repo
 
 	// If we parsed a tag from the link, we will try to look that up specifically
 	if tag != "" {
-		release, _, err = ghp.client.Repositories.GetReleaseByTag(ghp.ctx, owner, repo, tag)
+		release, _, err = ghp.client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
 	}
 
 	// If that fails, we'll fall back to the latest
 	if release == nil {
-		release, _, err = ghp.client.Repositories.GetLatestRelease(ghp.ctx, owner, repo)
+		release, _, err = ghp.client.Repositories.GetLatestRelease(ctx, owner, repo)
 	}
 
 	return
 }
 
 // GetJARDownloadLinksFromLink attempts to return a list of download links for a release's JARs
-func (ghp *GitHubProvider) GetJARDownloadLinksFromLink(link string) (downloadLinks []string, err error) {
+func (ghp *GitHubProvider) GetJARDownloadLinksFromLink(ctx context.Context, link string) (downloadLinks []string, err error) {
 
-	release, err := ghp.GetReleaseFromLink(link)
+	release, err := ghp.GetReleaseFromLink(ctx, link)
 	if err != nil {
 		return
 	}
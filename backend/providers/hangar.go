@@ -0,0 +1,191 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"geri.dev/pack-builder/config"
+	"geri.dev/pack-builder/utils"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var hangarBaseEndpoint = "https://hangar.papermc.io/api/v1"
+var hangarUserAccessibleEndpoint = "https://hangar.papermc.io"
+var hangarLinkRegex = regexp.MustCompile("https://hangar\\.papermc\\.io/(?P<owner>[^/]+)/(?P<slug>[^/?#]+)")
+
+type HangarProvider struct {
+	cfg *config.Config
+	c   *http.Client
+}
+
+func NewHangarProvider(cfg *config.Config) HangarProvider {
+	return HangarProvider{
+		cfg: cfg,
+		c:   &http.Client{},
+	}
+}
+
+// GetPluginProviderName returns the ID for the provider
+func (hp *HangarProvider) GetPluginProviderName() string {
+	return "hangar"
+}
+
+// makeRequest sends a new Hangar API request
+func (hp *HangarProvider) makeRequest(ctx context.Context, method, url string, result interface{}) error {
+	ctx, cancel := hp.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", hangarBaseEndpoint, url), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", hp.cfg.Credentials.UserAgent)
+
+	resp, err := hp.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get resource, status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+type hangarNamespace struct {
+	Owner string `json:"owner"`
+	Slug  string `json:"slug"`
+}
+
+type hangarPluginInfo struct {
+	Id          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Namespace   hangarNamespace `json:"namespace"`
+	Description string          `json:"description"`
+	AvatarUrl   string          `json:"avatarUrl"`
+}
+
+type hangarPluginDownload struct {
+	FileInfo *struct {
+		Name      string `json:"name"`
+		SizeBytes int64  `json:"sizeBytes"`
+	} `json:"fileInfo"`
+	ExternalUrl *string `json:"externalUrl"`
+	DownloadUrl *string `json:"downloadUrl"`
+}
+
+type hangarPluginVersionInfo struct {
+	Id                   int64                           `json:"id"`
+	Name                 string                          `json:"name"`
+	Downloads            map[string]hangarPluginDownload `json:"downloads"`
+	PlatformDependencies map[string][]string             `json:"platformDependencies"`
+}
+
+type hangarPluginVersions struct {
+	Result []hangarPluginVersionInfo `json:"result"`
+}
+
+// getPluginInfo gets the details and the versions
+// of a project from the Hangar API
+func (hp *HangarProvider) getPluginInfo(ctx context.Context, slug string) (info PluginInfo, err error) {
+
+	// Get the base project information
+	var rawInfo hangarPluginInfo
+	if err = hp.makeRequest(ctx, "GET", fmt.Sprintf("/projects/%s", slug), &rawInfo); err != nil {
+		return
+	}
+
+	// Get the version information
+	var rawVersions hangarPluginVersions
+	if err = hp.makeRequest(ctx, "GET", fmt.Sprintf("/projects/%s/versions?limit=25", slug), &rawVersions); err != nil {
+		return
+	}
+
+	link := fmt.Sprintf("%s/%s/%s", hangarUserAccessibleEndpoint, rawInfo.Namespace.Owner, rawInfo.Namespace.Slug)
+
+	// Each version can have a separate file for each platform,
+	// so we will treat them as separate versions
+	versions := make([]Version, 0)
+	for _, version := range rawVersions.Result {
+		for platform, download := range version.Downloads {
+			fileUrl := ""
+			if download.DownloadUrl != nil {
+				fileUrl = *download.DownloadUrl
+			} else if download.ExternalUrl != nil {
+				fileUrl = *download.ExternalUrl
+			}
+
+			var size int64
+			var fileName string
+			if download.FileInfo != nil {
+				size = download.FileInfo.SizeBytes
+				fileName = download.FileInfo.Name
+			}
+
+			versions = append(versions, Version{
+				Id:           fmt.Sprintf("%v", version.Id),
+				Link:         fmt.Sprintf("%s/versions/%s", link, version.Name),
+				IsExternal:   download.DownloadUrl == nil,
+				URL:          fileUrl,
+				Platforms:    []string{strings.ToLower(platform)},
+				GameVersions: version.PlatformDependencies[platform],
+				Size:         size,
+				FileName:     fileName,
+			})
+		}
+	}
+
+	info = PluginInfo{
+		Type:         Hangar,
+		Id:           fmt.Sprintf("%v", rawInfo.Id),
+		Link:         link,
+		Name:         rawInfo.Name,
+		Description:  rawInfo.Description,
+		Contributors: rawInfo.Namespace.Owner,
+		Versions:     versions,
+		IconLink:     rawInfo.AvatarUrl,
+	}
+
+	return
+}
+
+// GetPluginInfoFromLink attempts to parse the project slug of a link
+// and get its details from the Hangar API.
+func (hp *HangarProvider) GetPluginInfoFromLink(ctx context.Context, link string) (info PluginInfo, err error) {
+
+	// Parse the project slug
+	slug := utils.GetRegexGroup(hangarLinkRegex, "slug", link)
+	if slug == "" {
+		err = fmt.Errorf("unable to parse Hangar slug")
+		return
+	}
+
+	return hp.getPluginInfo(ctx, slug)
+}
+
+// GetPluginInfoFromProjectName attempts to get the details of a plugin
+// from a project's name. Hangar slugs are unique and usually match the
+// name of the plugin, so we will just look it up as one
+func (hp *HangarProvider) GetPluginInfoFromProjectName(ctx context.Context, name string) (info PluginInfo, err error) {
+	if info, err = hp.getPluginInfo(ctx, name); err != nil {
+		return
+	}
+
+	if strings.ToLower(info.Name) != strings.ToLower(name) {
+		info = PluginInfo{}
+		err = fmt.Errorf("no project found with this exact name")
+	}
+
+	return
+}
@@ -0,0 +1,502 @@
+package providers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"geri.dev/pack-builder/config"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// mavenPropertyRegex matches the ${property} placeholders of a POM
+var mavenPropertyRegex = regexp.MustCompile("\\$\\{([^}]+)}")
+
+// maxMavenParentDepth limits how many parent POMs we follow
+const maxMavenParentDepth = 10
+
+// MavenArtifact represents a single artifact of a Maven repository
+type MavenArtifact struct {
+	GroupId    string `json:"group_id"`
+	ArtifactId string `json:"artifact_id"`
+	Version    string `json:"version"`
+	Classifier string `json:"classifier,omitempty"`
+	Extension  string `json:"extension"`
+}
+
+// ParseMavenArtifact parses the coordinates of an artifact in the same
+// format Spigot's library loader accepts them
+// <groupId>:<artifactId>[:<extension>[:<classifier>]]:<version>
+func ParseMavenArtifact(coordinates string) (artifact MavenArtifact, err error) {
+	parts := strings.Split(strings.TrimSpace(coordinates), ":")
+	if len(parts) < 3 || len(parts) > 5 {
+		err = fmt.Errorf("invalid coordinates: %s", coordinates)
+		return
+	}
+
+	artifact = MavenArtifact{
+		GroupId:    parts[0],
+		ArtifactId: parts[1],
+		Version:    parts[len(parts)-1],
+		Extension:  "jar",
+	}
+
+	if len(parts) >= 4 {
+		artifact.Extension = parts[2]
+	}
+
+	if len(parts) == 5 {
+		artifact.Classifier = parts[3]
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			err = fmt.Errorf("invalid coordinates: %s", coordinates)
+			return
+		}
+	}
+
+	return
+}
+
+// String returns the coordinates of the artifact
+func (a MavenArtifact) String() string {
+	if a.Classifier != "" {
+		return fmt.Sprintf("%s:%s:%s:%s:%s", a.GroupId, a.ArtifactId, a.Extension, a.Classifier, a.Version)
+	}
+	return fmt.Sprintf("%s:%s:%s", a.GroupId, a.ArtifactId, a.Version)
+}
+
+// getKey returns what identifies the artifact regardless of its version
+func (a MavenArtifact) getKey() string {
+	return fmt.Sprintf("%s:%s:%s:%s", a.GroupId, a.ArtifactId, a.Extension, a.Classifier)
+}
+
+// GetPath returns the path of the artifact in the repository layout
+func (a MavenArtifact) GetPath() string {
+	return a.getFilePath(a.Classifier, a.Extension)
+}
+
+// GetPomPath returns the path of the artifact's POM in the repository layout
+func (a MavenArtifact) GetPomPath() string {
+	return a.getFilePath("", "pom")
+}
+
+// getFilePath returns the path of one of the artifact's files in the repository layout
+func (a MavenArtifact) getFilePath(classifier, extension string) string {
+	fileName := fmt.Sprintf("%s-%s", a.ArtifactId, a.Version)
+	if classifier != "" {
+		fileName += "-" + classifier
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s.%s", strings.ReplaceAll(a.GroupId, ".", "/"), a.ArtifactId, a.Version, fileName, extension)
+}
+
+// MavenFile represents a single file that has to be downloaded from a repository
+// POMs were already fetched during the resolution, so their data is kept
+type MavenFile struct {
+	Path string `json:"path"`
+	Sha1 string `json:"sha1,omitempty"`
+	Data []byte `json:"-"`
+}
+
+// MavenResolution represents all the files needed to load some libraries
+type MavenResolution struct {
+	Artifacts []MavenArtifact `json:"artifacts"`
+	Files     []MavenFile     `json:"files"`
+	Errors    []string        `json:"errors,omitempty"`
+}
+
+type mavenProperties map[string]string
+
+// UnmarshalXML reads each element of the properties as a key and a value
+func (p *mavenProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = make(mavenProperties)
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := d.DecodeElement(&value, &element); err != nil {
+				return err
+			}
+			(*p)[element.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type mavenExclusion struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+}
+
+type mavenDependency struct {
+	GroupId    string           `xml:"groupId"`
+	ArtifactId string           `xml:"artifactId"`
+	Version    string           `xml:"version"`
+	Type       string           `xml:"type"`
+	Classifier string           `xml:"classifier"`
+	Scope      string           `xml:"scope"`
+	Optional   string           `xml:"optional"`
+	Exclusions []mavenExclusion `xml:"exclusions>exclusion"`
+}
+
+type mavenPom struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Packaging  string `xml:"packaging"`
+	Parent     *struct {
+		GroupId    string `xml:"groupId"`
+		ArtifactId string `xml:"artifactId"`
+		Version    string `xml:"version"`
+	} `xml:"parent"`
+	Properties           mavenProperties   `xml:"properties"`
+	DependencyManagement []mavenDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Dependencies         []mavenDependency `xml:"dependencies>dependency"`
+}
+
+type MavenProvider struct {
+	cfg *config.Config
+	c   *http.Client
+}
+
+func NewMavenProvider(cfg *config.Config) MavenProvider {
+	return MavenProvider{
+		cfg: cfg,
+		c:   &http.Client{},
+	}
+}
+
+// GetFile returns the contents of a file from the resolution, downloading
+// it from the first repository that has it, if it was not fetched yet
+func (mp *MavenProvider) GetFile(ctx context.Context, file MavenFile) ([]byte, error) {
+	if file.Data != nil {
+		return file.Data, nil
+	}
+	return mp.fetch(ctx, file.Path)
+}
+
+// fetch downloads a file from the first repository that has it
+func (mp *MavenProvider) fetch(ctx context.Context, filePath string) (data []byte, err error) {
+	err = fmt.Errorf("no repositories configured")
+	for _, repository := range mp.cfg.Libraries.Repositories {
+		link := fmt.Sprintf("%s/%s", strings.TrimSuffix(repository, "/"), filePath)
+
+		if data, err = mp.fetchFromRepository(ctx, link); err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+// fetchFromRepository downloads a single file from a repository
+func (mp *MavenProvider) fetchFromRepository(ctx context.Context, link string) ([]byte, error) {
+	ctx, cancel := mp.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", mp.cfg.Credentials.UserAgent)
+
+	resp, err := mp.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s, status code: %d", link, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// fetchSha1 gets the SHA-1 checksum the repository lists for a file
+// Not every repository has them, so this is allowed to be empty
+func (mp *MavenProvider) fetchSha1(ctx context.Context, filePath string) string {
+	data, err := mp.fetch(ctx, filePath+".sha1")
+	if err != nil {
+		return ""
+	}
+
+	// Some checksum files also include the name of the file
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
+
+// mavenResolver keeps track of the POMs we have already
+// fetched while resolving a single set of libraries
+type mavenResolver struct {
+	provider *MavenProvider
+	poms     map[string]*mavenPom
+	files    map[string]MavenFile
+}
+
+// getEffectivePom fetches the POM of an artifact and merges it with its parents,
+// so it has every property and managed version it inherits
+func (r *mavenResolver) getEffectivePom(ctx context.Context, artifact MavenArtifact, depth int) (*mavenPom, error) {
+	pomPath := artifact.GetPomPath()
+	if pom, ok := r.poms[pomPath]; ok {
+		return pom, nil
+	}
+
+	if depth > maxMavenParentDepth {
+		return nil, fmt.Errorf("too many parent POMs for %s", artifact)
+	}
+
+	data, err := r.provider.fetch(ctx, pomPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var pom mavenPom
+	if err = xml.Unmarshal(data, &pom); err != nil {
+		return nil, fmt.Errorf("unable to parse POM of %s: %s", artifact, err)
+	}
+
+	// The library loader needs the POMs to resolve the libraries offline
+	r.files[pomPath] = MavenFile{Path: pomPath, Sha1: r.provider.fetchSha1(ctx, pomPath), Data: data}
+
+	properties := make(mavenProperties)
+	management := make([]mavenDependency, 0)
+
+	// Inherit everything from the parent, the values of the child take priority
+	if pom.Parent != nil {
+		parent, err := r.getEffectivePom(ctx, MavenArtifact{
+			GroupId:    pom.Parent.GroupId,
+			ArtifactId: pom.Parent.ArtifactId,
+			Version:    pom.Parent.Version,
+			Extension:  "pom",
+		}, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, value := range parent.Properties {
+			properties[key] = value
+		}
+
+		if pom.GroupId == "" {
+			pom.GroupId = parent.GroupId
+		}
+
+		if pom.Version == "" {
+			pom.Version = parent.Version
+		}
+
+		properties["project.parent.groupId"] = parent.GroupId
+		properties["project.parent.version"] = parent.Version
+		management = append(management, parent.DependencyManagement...)
+	}
+
+	for key, value := range pom.Properties {
+		properties[key] = value
+	}
+
+	properties["project.groupId"] = pom.GroupId
+	properties["project.artifactId"] = pom.ArtifactId
+	properties["project.version"] = pom.Version
+	properties["pom.groupId"] = pom.GroupId
+	properties["pom.version"] = pom.Version
+	pom.Properties = properties
+
+	// The managed versions of the child have to be checked first
+	pom.DependencyManagement = append(r.interpolateDependencies(pom.DependencyManagement, properties), management...)
+	pom.Dependencies = r.interpolateDependencies(pom.Dependencies, properties)
+
+	// Bills of materials are imported into the managed versions
+	imported := make([]mavenDependency, 0)
+	for _, dependency := range pom.DependencyManagement {
+		if dependency.Scope != "import" || dependency.Type != "pom" {
+			continue
+		}
+
+		bom, err := r.getEffectivePom(ctx, MavenArtifact{
+			GroupId:    dependency.GroupId,
+			ArtifactId: dependency.ArtifactId,
+			Version:    dependency.Version,
+			Extension:  "pom",
+		}, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		imported = append(imported, bom.DependencyManagement...)
+	}
+	pom.DependencyManagement = append(pom.DependencyManagement, imported...)
+
+	r.poms[pomPath] = &pom
+	return &pom, nil
+}
+
+// interpolate replaces the property placeholders of a value
+// Properties can refer to other properties, so we will do it a few times
+func (r *mavenResolver) interpolate(value string, properties mavenProperties) string {
+	for i := 0; i < 5 && strings.Contains(value, "${"); i++ {
+		value = mavenPropertyRegex.ReplaceAllStringFunc(value, func(placeholder string) string {
+			if replacement, ok := properties[placeholder[2:len(placeholder)-1]]; ok {
+				return replacement
+			}
+			return placeholder
+		})
+	}
+	return value
+}
+
+// interpolateDependencies replaces the property placeholders of each dependency
+func (r *mavenResolver) interpolateDependencies(dependencies []mavenDependency, properties mavenProperties) []mavenDependency {
+	result := make([]mavenDependency, 0)
+	for _, dependency := range dependencies {
+		dependency.GroupId = r.interpolate(dependency.GroupId, properties)
+		dependency.ArtifactId = r.interpolate(dependency.ArtifactId, properties)
+		dependency.Version = r.interpolate(dependency.Version, properties)
+		dependency.Type = r.interpolate(dependency.Type, properties)
+		dependency.Classifier = r.interpolate(dependency.Classifier, properties)
+		dependency.Scope = r.interpolate(dependency.Scope, properties)
+		result = append(result, dependency)
+	}
+	return result
+}
+
+// toArtifact converts a dependency of a POM into the artifact it refers to
+func (d mavenDependency) toArtifact() MavenArtifact {
+	artifact := MavenArtifact{
+		GroupId:    d.GroupId,
+		ArtifactId: d.ArtifactId,
+		Version:    d.Version,
+		Classifier: d.Classifier,
+		Extension:  "jar",
+	}
+
+	switch d.Type {
+	case "pom":
+		artifact.Extension = "pom"
+	case "test-jar":
+		artifact.Classifier = "tests"
+	}
+
+	return artifact
+}
+
+// mavenNode represents an artifact waiting to be resolved, along with
+// the exclusions of the dependencies that pulled it in
+type mavenNode struct {
+	artifact   MavenArtifact
+	exclusions []mavenExclusion
+}
+
+// isExcluded returns true if one of the exclusions matches the dependency
+func (n mavenNode) isExcluded(dependency mavenDependency) bool {
+	for _, exclusion := range n.exclusions {
+		if (exclusion.GroupId == "*" || exclusion.GroupId == dependency.GroupId) &&
+			(exclusion.ArtifactId == "*" || exclusion.ArtifactId == dependency.ArtifactId) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveLibraries resolves each library and their runtime dependencies
+// the same way Maven does, where the nearest version of an artifact wins,
+// and returns every file the library loader needs to load them offline
+func (mp *MavenProvider) ResolveLibraries(ctx context.Context, libraries []MavenArtifact) (resolution MavenResolution) {
+	resolver := mavenResolver{
+		provider: mp,
+		poms:     make(map[string]*mavenPom),
+		files:    make(map[string]MavenFile),
+	}
+
+	queue := make([]mavenNode, 0)
+	for _, library := range libraries {
+		queue = append(queue, mavenNode{artifact: library})
+	}
+
+	resolved := make(map[string]bool)
+	for len(queue) > 0 {
+
+		// Stop resolving once we have been cancelled
+		if err := ctx.Err(); err != nil {
+			resolution.Errors = append(resolution.Errors, err.Error())
+			break
+		}
+
+		node := queue[0]
+		queue = queue[1:]
+
+		if resolved[node.artifact.getKey()] {
+			continue
+		}
+		resolved[node.artifact.getKey()] = true
+
+		pom, err := resolver.getEffectivePom(ctx, node.artifact, 0)
+		if err != nil {
+			resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: %s", node.artifact, err))
+			continue
+		}
+
+		resolution.Artifacts = append(resolution.Artifacts, node.artifact)
+		if node.artifact.Extension != "pom" {
+			artifactPath := node.artifact.GetPath()
+			resolver.files[artifactPath] = MavenFile{Path: artifactPath, Sha1: mp.fetchSha1(ctx, artifactPath)}
+		}
+
+		for _, dependency := range pom.Dependencies {
+			switch dependency.Scope {
+			case "test", "provided", "system", "import":
+				continue
+			}
+
+			if dependency.Optional == "true" || node.isExcluded(dependency) {
+				continue
+			}
+
+			// The version can be managed by the POM or one of its parents
+			if dependency.Version == "" {
+				for _, managed := range pom.DependencyManagement {
+					if managed.GroupId == dependency.GroupId && managed.ArtifactId == dependency.ArtifactId {
+						dependency.Version = managed.Version
+						break
+					}
+				}
+			}
+
+			if dependency.Version == "" || strings.ContainsAny(dependency.Version, "[($") {
+				resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: unable to determine the version of %s:%s", node.artifact, dependency.GroupId, dependency.ArtifactId))
+				continue
+			}
+
+			queue = append(queue, mavenNode{
+				artifact:   dependency.toArtifact(),
+				exclusions: append(append([]mavenExclusion{}, node.exclusions...), dependency.Exclusions...),
+			})
+		}
+	}
+
+	for _, file := range resolver.files {
+		resolution.Files = append(resolution.Files, file)
+	}
+
+	sort.Slice(resolution.Files, func(i, j int) bool {
+		return resolution.Files[i].Path < resolution.Files[j].Path
+	})
+
+	return
+}
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"geri.dev/pack-builder/config"
@@ -8,11 +9,12 @@ import (
 	"io"
 	"net/http"
 	"regexp"
+	"strings"
 )
 
 var modrinthBaseEndpoint = "https://api.modrinth.com/v2"
 var modrinthUserAccessibleEndpoint = "https://modrinth.com"
-var modrinthLinkRegex = regexp.MustCompile("https://modrinth\\.com/plugin/(?P<slug>.+)")
+var modrinthLinkRegex = regexp.MustCompile("https://modrinth\\.com/(?:plugin|mod|datapack|resourcepack)/(?P<slug>[^/?#]+)(?:/version/(?P<version>[^/?#]+))?")
 
 type ModrinthProvider struct {
 	cfg *config.Config
@@ -32,8 +34,11 @@ func (mp *ModrinthProvider) GetPluginProviderName() string {
 }
 
 // makeRequest sends a new Modrinth API request
-func (mp *ModrinthProvider) makeRequest(method, url string, result interface{}) error {
-	req, err := http.NewRequest(method, fmt.Sprintf("%s%s", modrinthBaseEndpoint, url), nil)
+func (mp *ModrinthProvider) makeRequest(ctx context.Context, method, url string, result interface{}) error {
+	ctx, cancel := mp.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", modrinthBaseEndpoint, url), nil)
 	if err != nil {
 		return err
 	}
@@ -65,6 +70,9 @@ type modrinthPluginInfo struct {
 	Slug         string   `json:"slug"`
 	Title        string   `json:"title"`
 	Description  string   `json:"description"`
+	ProjectType  string   `json:"project_type"`
+	ClientSide   string   `json:"client_side"`
+	ServerSide   string   `json:"server_side"`
 	GameVersions []string `json:"game_versions"`
 	Loaders      []string `json:"loaders"`
 	VersionIds   []string `json:"versions"`
@@ -73,7 +81,7 @@ type modrinthPluginInfo struct {
 
 type modrinthPluginFile struct {
 	Url      string `json:"url"`
-	FileName string `json:"file_name"`
+	FileName string `json:"filename"`
 	Size     int64  `json:"size"`
 	Primary  bool   `json:"primary"`
 }
@@ -97,29 +105,49 @@ type modrinthPluginVersionInfo struct {
 
 // GetPluginInfoFromLink attempts to parse the project ID of a link
 // and get its details from the Modrinth API.
-func (mp *ModrinthProvider) GetPluginInfoFromLink(link string) (info PluginInfo, err error) {
+// If the link points to a specific version, only that one will be returned
+func (mp *ModrinthProvider) GetPluginInfoFromLink(ctx context.Context, link string) (info PluginInfo, err error) {
 
 	// Parse the resource ID
-	slug := utils.GetRegexGroup(modrinthLinkRegex, "slug", link)
+	groups := utils.GetRegexGroups(modrinthLinkRegex, link)
+	slug := groups["slug"]
 	if slug == "" {
 		err = fmt.Errorf("unable to parse Modrinth slug")
 		return
 	}
 
+	return mp.getPluginInfo(ctx, slug, groups["version"])
+}
+
+// GetPluginInfoFromProjectName attempts to get the details of a project
+// from its name. Modrinth slugs are unique and are usually the same
+// as the name of the plugin or the ID of the mod, so we will look it up as one
+func (mp *ModrinthProvider) GetPluginInfoFromProjectName(ctx context.Context, name string) (info PluginInfo, err error) {
+	return mp.getPluginInfo(ctx, strings.ReplaceAll(strings.ToLower(name), " ", "-"), "")
+}
+
+// getPluginInfo gets the details and the versions of a project from the Modrinth API
+// If a version ID or number is passed, only that specific version will be returned
+func (mp *ModrinthProvider) getPluginInfo(ctx context.Context, slug, pinnedVersion string) (info PluginInfo, err error) {
+
 	// Get the base project information
 	var rawInfo modrinthPluginInfo
-	if err = mp.makeRequest("GET", fmt.Sprintf("/project/%s", slug), &rawInfo); err != nil {
+	if err = mp.makeRequest(ctx, "GET", fmt.Sprintf("/project/%s", slug), &rawInfo); err != nil {
 		return PluginInfo{}, err
 	}
 
 	// Get the version information
 	var rawVersions []modrinthPluginVersionInfo
-	if err = mp.makeRequest("GET", fmt.Sprintf("/project/%s/version", slug), &rawVersions); err != nil {
+	if err = mp.makeRequest(ctx, "GET", fmt.Sprintf("/project/%s/version", slug), &rawVersions); err != nil {
 		return PluginInfo{}, err
 	}
 
 	versions := make([]Version, 0)
 	for _, version := range rawVersions {
+		if pinnedVersion != "" && version.Id != pinnedVersion && version.VersionNumber != pinnedVersion {
+			continue
+		}
+
 		var primaryFile *modrinthPluginFile
 		for _, file := range version.Files {
 			if file.Primary {
@@ -132,31 +160,45 @@ func (mp *ModrinthProvider) GetPluginInfoFromLink(link string) (info PluginInfo,
 			continue
 		}
 
+		// Keep track of the projects this version can't be used with
+		incompatible := make([]string, 0)
+		for _, dependency := range version.Dependencies {
+			if dependency.DependencyType == "incompatible" && dependency.ProjectId != nil {
+				incompatible = append(incompatible, *dependency.ProjectId)
+			}
+		}
+
+		// Resource packs are only marked as being for the game itself
+		platforms := version.Loaders
+		if rawInfo.ProjectType == "resourcepack" {
+			platforms = []string{ResourcePackLoader}
+		}
+
 		versions = append(versions, Version{
 			Id:           version.Id,
 			Link:         fmt.Sprintf("%s/%s/version/%s", modrinthUserAccessibleEndpoint, rawInfo.Slug, version.Id),
 			IsExternal:   false,
 			URL:          primaryFile.Url,
-			Platforms:    version.Loaders,
+			Platforms:    platforms,
 			GameVersions: version.GameVersions,
+			Incompatible: incompatible,
+			Size:         primaryFile.Size,
+			FileName:     primaryFile.FileName,
 		})
 	}
 
 	info = PluginInfo{
 		Type:         Modrinth,
 		Id:           fmt.Sprintf("%v", rawInfo.Id),
-		Link:         fmt.Sprintf("%s/plugin/%s", modrinthUserAccessibleEndpoint, rawInfo.Slug),
+		Link:         fmt.Sprintf("%s/%s/%s", modrinthUserAccessibleEndpoint, rawInfo.ProjectType, rawInfo.Slug),
 		Name:         rawInfo.Title,
 		Description:  rawInfo.Description,
 		Contributors: rawInfo.TeamId,
 		Versions:     versions,
 		IconLink:     rawInfo.IconUrl,
+		ClientSide:   rawInfo.ClientSide,
+		ServerSide:   rawInfo.ServerSide,
 	}
 
 	return
 }
-
-func (mp *ModrinthProvider) GetPluginInfoFromProjectName(name string) (info PluginInfo, err error) {
-	err = fmt.Errorf("fuck you")
-	return
-}
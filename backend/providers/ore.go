@@ -0,0 +1,253 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"geri.dev/pack-builder/config"
+	"geri.dev/pack-builder/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var oreBaseEndpoint = "https://ore.spongepowered.org/api/v2"
+var oreUserAccessibleEndpoint = "https://ore.spongepowered.org"
+var oreLinkRegex = regexp.MustCompile("https://ore\\.spongepowered\\.org/(?P<owner>[^/]+)/(?P<slug>[^/?#]+)")
+
+type OreProvider struct {
+	cfg *config.Config
+	c   *http.Client
+
+	// Ore requires a session for every API call,
+	// which we will reuse until it expires
+	sessionLock    *sync.Mutex
+	session        string
+	sessionExpires time.Time
+}
+
+func NewOreProvider(cfg *config.Config) OreProvider {
+	return OreProvider{
+		cfg:         cfg,
+		c:           &http.Client{},
+		sessionLock: &sync.Mutex{},
+	}
+}
+
+// GetPluginProviderName returns the ID for the provider
+func (op *OreProvider) GetPluginProviderName() string {
+	return "ore"
+}
+
+type oreSession struct {
+	Session string    `json:"session"`
+	Expires time.Time `json:"expires"`
+}
+
+// getSession returns a valid Ore API session, authenticating
+// with the API key if we have one or anonymously if not
+func (op *OreProvider) getSession(ctx context.Context) (string, error) {
+	op.sessionLock.Lock()
+	defer op.sessionLock.Unlock()
+
+	if op.session != "" && time.Now().Add(time.Minute).Before(op.sessionExpires) {
+		return op.session, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/authenticate", oreBaseEndpoint), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if op.cfg.Credentials.Ore.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("OreApi apikey=\"%s\"", op.cfg.Credentials.Ore.Token))
+	}
+	req.Header.Set("User-Agent", op.cfg.Credentials.UserAgent)
+
+	resp, err := op.c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to authenticate, status code: %d", resp.StatusCode)
+	}
+
+	var session oreSession
+	if err = json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return "", err
+	}
+
+	op.session = session.Session
+	op.sessionExpires = session.Expires
+	return op.session, nil
+}
+
+// makeRequest sends a new Ore API request
+func (op *OreProvider) makeRequest(ctx context.Context, method, url string, result interface{}) error {
+	ctx, cancel := op.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
+	session, err := op.getSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", oreBaseEndpoint, url), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("OreApi session=\"%s\"", session))
+	req.Header.Set("User-Agent", op.cfg.Credentials.UserAgent)
+
+	resp, err := op.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get resource, status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+type oreNamespace struct {
+	Owner string `json:"owner"`
+	Slug  string `json:"slug"`
+}
+
+type orePluginInfo struct {
+	PluginId  string       `json:"plugin_id"`
+	Name      string       `json:"name"`
+	Namespace oreNamespace `json:"namespace"`
+	Summary   string       `json:"summary"`
+	IconUrl   string       `json:"icon_url"`
+}
+
+type orePluginSearch struct {
+	Result []orePluginInfo `json:"result"`
+}
+
+type orePluginVersionInfo struct {
+	Name     string `json:"name"`
+	FileInfo struct {
+		Name string `json:"name"`
+	} `json:"file_info"`
+	Tags struct {
+		Platforms []struct {
+			Platform         string `json:"platform"`
+			PlatformVersion  string `json:"platform_version"`
+			MinecraftVersion string `json:"minecraft_version"`
+		} `json:"platforms"`
+	} `json:"tags"`
+}
+
+type orePluginVersions struct {
+	Result []orePluginVersionInfo `json:"result"`
+}
+
+// getPluginInfo gets the details and the versions
+// of a project from the Ore API by its plugin ID
+func (op *OreProvider) getPluginInfo(ctx context.Context, rawInfo orePluginInfo) (info PluginInfo, err error) {
+
+	// Get the version information
+	var rawVersions orePluginVersions
+	if err = op.makeRequest(ctx, "GET", fmt.Sprintf("/projects/%s/versions?limit=25", rawInfo.PluginId), &rawVersions); err != nil {
+		return
+	}
+
+	link := fmt.Sprintf("%s/%s/%s", oreUserAccessibleEndpoint, rawInfo.Namespace.Owner, rawInfo.Namespace.Slug)
+
+	versions := make([]Version, 0)
+	for _, version := range rawVersions.Result {
+
+		// Ore lists the Minecraft version next to the Sponge API version
+		gameVersions := make([]string, 0)
+		for _, platform := range version.Tags.Platforms {
+			if platform.MinecraftVersion != "" {
+				gameVersions = append(gameVersions, platform.MinecraftVersion)
+			}
+		}
+
+		versions = append(versions, Version{
+			Id:           version.Name,
+			Link:         fmt.Sprintf("%s/versions/%s", link, url.PathEscape(version.Name)),
+			IsExternal:   false,
+			URL:          fmt.Sprintf("%s/versions/%s/download", link, url.PathEscape(version.Name)),
+			Platforms:    []string{"sponge"},
+			GameVersions: gameVersions,
+		})
+	}
+
+	info = PluginInfo{
+		Type:         Ore,
+		Id:           rawInfo.PluginId,
+		Link:         link,
+		Name:         rawInfo.Name,
+		Description:  rawInfo.Summary,
+		Contributors: rawInfo.Namespace.Owner,
+		Versions:     versions,
+		IconLink:     rawInfo.IconUrl,
+	}
+
+	return
+}
+
+// searchPlugins searches Ore for projects and returns the
+// first one that matches the given condition
+func (op *OreProvider) searchPlugins(ctx context.Context, query string, matches func(orePluginInfo) bool) (info PluginInfo, err error) {
+	var plugins orePluginSearch
+	if err = op.makeRequest(ctx, "GET", fmt.Sprintf("/projects?q=%s", url.QueryEscape(query)), &plugins); err != nil {
+		return
+	}
+
+	for _, plugin := range plugins.Result {
+		if matches(plugin) {
+			return op.getPluginInfo(ctx, plugin)
+		}
+	}
+
+	err = fmt.Errorf("no project found")
+	return
+}
+
+// GetPluginInfoFromLink attempts to parse the owner and the slug of
+// a link and get its details from the Ore API. Ore's API uses plugin IDs
+// instead of slugs, so we will have to search for it first
+func (op *OreProvider) GetPluginInfoFromLink(ctx context.Context, link string) (info PluginInfo, err error) {
+
+	// Parse the namespace
+	groups := utils.GetRegexGroups(oreLinkRegex, link)
+	owner := groups["owner"]
+	slug := groups["slug"]
+	if owner == "" || slug == "" {
+		err = fmt.Errorf("unable to parse Ore slug")
+		return
+	}
+
+	return op.searchPlugins(ctx, slug, func(plugin orePluginInfo) bool {
+		return strings.EqualFold(plugin.Namespace.Owner, owner) && strings.EqualFold(plugin.Namespace.Slug, slug)
+	})
+}
+
+// GetPluginInfoFromProjectName attempts to get the details of a plugin
+// from a project's name. Sponge plugins depend on each other using their
+// IDs, so we will accept either of them
+func (op *OreProvider) GetPluginInfoFromProjectName(ctx context.Context, name string) (info PluginInfo, err error) {
+	return op.searchPlugins(ctx, name, func(plugin orePluginInfo) bool {
+		return strings.EqualFold(plugin.PluginId, name) || strings.EqualFold(plugin.Name, name)
+	})
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"geri.dev/pack-builder/config"
+	"io"
+	"net/http"
+)
+
+var paperMCBaseEndpoint = "https://api.papermc.io/v2"
+
+type PaperMCProvider struct {
+	cfg *config.Config
+	c   *http.Client
+}
+
+func NewPaperMCProvider(cfg *config.Config) PaperMCProvider {
+	return PaperMCProvider{
+		cfg: cfg,
+		c:   &http.Client{},
+	}
+}
+
+// GetServerProviderName returns the ID for the server provider
+func (pp *PaperMCProvider) GetServerProviderName() string {
+	return "papermc"
+}
+
+// makeRequest sends a new PaperMC API request
+func (pp *PaperMCProvider) makeRequest(ctx context.Context, method, url string, result interface{}) error {
+	ctx, cancel := pp.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", paperMCBaseEndpoint, url), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", pp.cfg.Credentials.UserAgent)
+
+	resp, err := pp.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get resource, status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+type paperMCProjectInfo struct {
+	Versions []string `json:"versions"`
+}
+
+type paperMCVersionInfo struct {
+	Builds []int64 `json:"builds"`
+}
+
+type paperMCBuildInfo struct {
+	Build     int64 `json:"build"`
+	Downloads struct {
+		Application struct {
+			Name   string `json:"name"`
+			Sha256 string `json:"sha256"`
+		} `json:"application"`
+	} `json:"downloads"`
+}
+
+// GetServerJar looks up a specific build of a PaperMC project, such as Paper,
+// Folia or Velocity. If the version or the build is empty, the latest one is used
+func (pp *PaperMCProvider) GetServerJar(ctx context.Context, project, gameVersion, build string) (jar ServerJar, err error) {
+
+	// If we don't have a version, we will use the newest one
+	if gameVersion == "" {
+		var rawProject paperMCProjectInfo
+		if err = pp.makeRequest(ctx, "GET", fmt.Sprintf("/projects/%s", project), &rawProject); err != nil {
+			return
+		}
+
+		if len(rawProject.Versions) == 0 {
+			err = fmt.Errorf("no versions found for %s", project)
+			return
+		}
+
+		gameVersion = rawProject.Versions[len(rawProject.Versions)-1]
+	}
+
+	// If we don't have a build, we will use the newest one
+	if build == "" {
+		var rawVersion paperMCVersionInfo
+		if err = pp.makeRequest(ctx, "GET", fmt.Sprintf("/projects/%s/versions/%s", project, gameVersion), &rawVersion); err != nil {
+			return
+		}
+
+		if len(rawVersion.Builds) == 0 {
+			err = fmt.Errorf("no builds found for %s %s", project, gameVersion)
+			return
+		}
+
+		build = fmt.Sprintf("%v", rawVersion.Builds[len(rawVersion.Builds)-1])
+	}
+
+	// Get the build's download information
+	var rawBuild paperMCBuildInfo
+	if err = pp.makeRequest(ctx, "GET", fmt.Sprintf("/projects/%s/versions/%s/builds/%s", project, gameVersion, build), &rawBuild); err != nil {
+		return
+	}
+
+	application := rawBuild.Downloads.Application
+	if application.Name == "" {
+		err = fmt.Errorf("no server JAR found for %s %s build %s", project, gameVersion, build)
+		return
+	}
+
+	jar = ServerJar{
+		Project:  project,
+		Version:  gameVersion,
+		Build:    fmt.Sprintf("%v", rawBuild.Build),
+		FileName: application.Name,
+		URL: fmt.Sprintf("%s/projects/%s/versions/%s/builds/%v/downloads/%s",
+			paperMCBaseEndpoint, project, gameVersion, rawBuild.Build, application.Name),
+		Checksum: Checksum{Algorithm: "sha256", Hash: application.Sha256},
+	}
+
+	return
+}
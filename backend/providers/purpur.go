@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"geri.dev/pack-builder/config"
+	"io"
+	"net/http"
+)
+
+var purpurBaseEndpoint = "https://api.purpurmc.org/v2"
+
+type PurpurProvider struct {
+	cfg *config.Config
+	c   *http.Client
+}
+
+func NewPurpurProvider(cfg *config.Config) PurpurProvider {
+	return PurpurProvider{
+		cfg: cfg,
+		c:   &http.Client{},
+	}
+}
+
+// GetServerProviderName returns the ID for the server provider
+func (pp *PurpurProvider) GetServerProviderName() string {
+	return "purpur"
+}
+
+// makeRequest sends a new Purpur API request
+func (pp *PurpurProvider) makeRequest(ctx context.Context, method, url string, result interface{}) error {
+	ctx, cancel := pp.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", purpurBaseEndpoint, url), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", pp.cfg.Credentials.UserAgent)
+
+	resp, err := pp.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get resource, status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+type purpurProjectInfo struct {
+	Versions []string `json:"versions"`
+}
+
+type purpurVersionInfo struct {
+	Builds struct {
+		Latest string   `json:"latest"`
+		All    []string `json:"all"`
+	} `json:"builds"`
+}
+
+type purpurBuildInfo struct {
+	Build  string `json:"build"`
+	Result string `json:"result"`
+	Md5    string `json:"md5"`
+}
+
+// GetServerJar looks up a specific Purpur build.
+// If the version or the build is empty, the latest one is used
+func (pp *PurpurProvider) GetServerJar(ctx context.Context, project, gameVersion, build string) (jar ServerJar, err error) {
+
+	// If we don't have a version, we will use the newest one
+	if gameVersion == "" {
+		var rawProject purpurProjectInfo
+		if err = pp.makeRequest(ctx, "GET", fmt.Sprintf("/%s", project), &rawProject); err != nil {
+			return
+		}
+
+		if len(rawProject.Versions) == 0 {
+			err = fmt.Errorf("no versions found for %s", project)
+			return
+		}
+
+		gameVersion = rawProject.Versions[len(rawProject.Versions)-1]
+	}
+
+	// If we don't have a build, we will use the newest one
+	if build == "" {
+		var rawVersion purpurVersionInfo
+		if err = pp.makeRequest(ctx, "GET", fmt.Sprintf("/%s/%s", project, gameVersion), &rawVersion); err != nil {
+			return
+		}
+
+		if rawVersion.Builds.Latest == "" {
+			err = fmt.Errorf("no builds found for %s %s", project, gameVersion)
+			return
+		}
+
+		build = rawVersion.Builds.Latest
+	}
+
+	// Ensure the build actually exists and succeeded
+	var rawBuild purpurBuildInfo
+	if err = pp.makeRequest(ctx, "GET", fmt.Sprintf("/%s/%s/%s", project, gameVersion, build), &rawBuild); err != nil {
+		return
+	}
+
+	if rawBuild.Result != "SUCCESS" {
+		err = fmt.Errorf("build %s of %s %s was not successful", build, project, gameVersion)
+		return
+	}
+
+	jar = ServerJar{
+		Project:  project,
+		Version:  gameVersion,
+		Build:    rawBuild.Build,
+		FileName: fmt.Sprintf("%s-%s-%s.jar", project, gameVersion, rawBuild.Build),
+		URL:      fmt.Sprintf("%s/%s/%s/%s/download", purpurBaseEndpoint, project, gameVersion, rawBuild.Build),
+		Checksum: Checksum{Algorithm: "md5", Hash: rawBuild.Md5},
+	}
+
+	return
+}
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"geri.dev/pack-builder/config"
@@ -97,10 +98,13 @@ func (sp *SpigotProvider) GetPluginProviderName() string {
 }
 
 // makeRequest sends a new Spiget API request
-func (sp *SpigotProvider) makeRequest(method, url string, result interface{}) error {
+func (sp *SpigotProvider) makeRequest(ctx context.Context, method, url string, result interface{}) error {
 	fmt.Println("that's an api call right there") // Todo (notgeri):
 
-	req, err := http.NewRequest(method, fmt.Sprintf("%s%s", spigotBaseEndpoint, url), nil)
+	ctx, cancel := sp.cfg.Quotas.RequestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", spigotBaseEndpoint, url), nil)
 	if err != nil {
 		return err
 	}
@@ -129,7 +133,7 @@ func (sp *SpigotProvider) makeRequest(method, url string, result interface{}) er
 // and get its details from the Spiget API.
 // If there are any issues reaching the API or parsing the response,
 // we will simply return an error
-func (sp *SpigotProvider) GetPluginInfoFromLink(link string) (info PluginInfo, err error) {
+func (sp *SpigotProvider) GetPluginInfoFromLink(ctx context.Context, link string) (info PluginInfo, err error) {
 
 	// Parse the resource ID
 	id := utils.GetRegexGroup(spigotLinkRegex, "id", link)
@@ -140,7 +144,7 @@ func (sp *SpigotProvider) GetPluginInfoFromLink(link string) (info PluginInfo, e
 
 	// Get the resource by its ID
 	var rawInfo spigetInfo
-	if err = sp.makeRequest("GET", fmt.Sprintf("/resources/%s", id), &rawInfo); err != nil {
+	if err = sp.makeRequest(ctx, "GET", fmt.Sprintf("/resources/%s", id), &rawInfo); err != nil {
 		return
 	} else { // Convert it to a generic plugin info
 		info = rawInfo.ToPluginInfo()
@@ -153,11 +157,11 @@ func (sp *SpigotProvider) GetPluginInfoFromLink(link string) (info PluginInfo, e
 // from a project's name. Sadly, if there are several project with the same
 // name, we can't guarantee it is the specific one, so we'll just sort by downloads
 // and ask the user to confirm
-func (sp *SpigotProvider) GetPluginInfoFromProjectName(name string) (info PluginInfo, err error) {
+func (sp *SpigotProvider) GetPluginInfoFromProjectName(ctx context.Context, name string) (info PluginInfo, err error) {
 
 	// Get the resource by its ID
 	var plugins []spigetInfo
-	if err = sp.makeRequest("GET", fmt.Sprintf("/search/resources/%s?field=name&sort=-downloads", name), &plugins); err != nil {
+	if err = sp.makeRequest(ctx, "GET", fmt.Sprintf("/search/resources/%s?field=name&sort=-downloads", name), &plugins); err != nil {
 		return
 	} else {
 		// Convert the first result that has that exact name into a generic plugin info
@@ -1,31 +1,48 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"github.com/hashicorp/go-version"
 	"strings"
 )
 
 type ExternalProvider interface {
-	GetJARDownloadLinksFromLink(string) ([]string, error)
+	GetJARDownloadLinksFromLink(context.Context, string) ([]string, error)
 	GetExternalProviderName() string
 }
 
 type PluginProvider interface {
-	GetPluginInfoFromLink(string) (PluginInfo, error)
-	GetPluginInfoFromProjectName(string) (PluginInfo, error)
+	GetPluginInfoFromLink(context.Context, string) (PluginInfo, error)
+	GetPluginInfoFromProjectName(context.Context, string) (PluginInfo, error)
 	GetPluginProviderName() string
 }
 
 type ModProvider interface {
-	GetModInfoFromLink(string) (PluginInfo, error)
+	GetModInfoFromLink(context.Context, string) (PluginInfo, error)
+}
+
+type ServerProvider interface {
+	GetServerJar(ctx context.Context, project, gameVersion, build string) (ServerJar, error)
+	GetServerProviderName() string
 }
 
 type PluginType string
 
 const (
-	Spigot   PluginType = "spigot"
-	Modrinth PluginType = "modrinth"
+	Spigot     PluginType = "spigot"
+	Modrinth   PluginType = "modrinth"
+	Hangar     PluginType = "hangar"
+	Ore        PluginType = "ore"
+	CurseForge PluginType = "curseforge"
+	Direct     PluginType = "direct"
+)
+
+// The loaders we use for projects that are loaded
+// by the game itself rather than a platform
+const (
+	DatapackLoader     = "datapack"
+	ResourcePackLoader = "resourcepack"
 )
 
 type Version struct {
@@ -35,6 +52,13 @@ type Version struct {
 	URL          string   `json:"url"`
 	Platforms    []string `json:"platforms"`
 	GameVersions []string `json:"game_versions"`
+	Incompatible []string `json:"incompatible,omitempty"`
+
+	// The size of the file in bytes, if the provider specifies it
+	Size int64 `json:"size,omitempty"`
+
+	// The name of the file, if the provider specifies it
+	FileName string `json:"file_name,omitempty"`
 }
 
 type PluginInfo struct {
@@ -47,6 +71,27 @@ type PluginInfo struct {
 	Premium      bool       `json:"premium"`
 	Versions     []Version  `json:"versions"`
 	IconLink     string     `json:"icon_link"`
+
+	// Whether the project is required, optional or unsupported on each side
+	// Only some providers specify this, otherwise it's empty
+	ClientSide string `json:"client_side,omitempty"`
+	ServerSide string `json:"server_side,omitempty"`
+}
+
+// Checksum represents a hash provided by an API for a file
+type Checksum struct {
+	Algorithm string `json:"algorithm"`
+	Hash      string `json:"hash"`
+}
+
+// ServerJar represents a single downloadable server software build
+type ServerJar struct {
+	Project  string   `json:"project"`
+	Version  string   `json:"version"`
+	Build    string   `json:"build"`
+	FileName string   `json:"file_name"`
+	URL      string   `json:"url"`
+	Checksum Checksum `json:"checksum"`
 }
 
 // IsTestedVersion returns whether a provided version is marked as tested
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"github.com/hashicorp/go-version"
+	"io"
+	"strings"
+)
+
+// classFileMagic is the magic number every Java class file starts with
+const classFileMagic = 0xCAFEBABE
+
+// classFileVersionOffset is the difference between the major
+// version of a class file and the Java version it was compiled for
+const classFileVersionOffset = 44
+
+// GetRequiredJavaVersion reads the class files of a JAR to determine
+// the minimum Java version it needs to run
+// Classes in the versioned folders of Multi-Release JARs are only loaded
+// on newer Java versions, so those are not counted towards the minimum
+func GetRequiredJavaVersion(filePath string) (javaVersion int, err error) {
+	zipReader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return
+	}
+	defer zipReader.Close()
+
+	for _, file := range zipReader.File {
+		if !strings.HasSuffix(file.Name, ".class") || strings.HasPrefix(file.Name, "META-INF/") {
+			continue
+		}
+
+		// Module descriptors are often compiled for a newer version
+		// than the rest of the classes, and are ignored by older ones
+		if path := strings.Split(file.Name, "/"); path[len(path)-1] == "module-info.class" {
+			continue
+		}
+
+		classVersion, classErr := readClassJavaVersion(file)
+		if classErr != nil {
+			continue
+		}
+
+		if classVersion > javaVersion {
+			javaVersion = classVersion
+		}
+	}
+
+	if javaVersion == 0 {
+		err = fmt.Errorf("no class files found")
+	}
+
+	return
+}
+
+// readClassJavaVersion reads the header of a class file and
+// returns the Java version it was compiled for
+func readClassJavaVersion(file *zip.File) (int, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	// The header is the magic number, followed by the minor and the major versions
+	header := make([]byte, 8)
+	if _, err = io.ReadFull(rc, header); err != nil {
+		return 0, err
+	}
+
+	if binary.BigEndian.Uint32(header[0:4]) != classFileMagic {
+		return 0, fmt.Errorf("not a valid class file")
+	}
+
+	return int(binary.BigEndian.Uint16(header[6:8])) - classFileVersionOffset, nil
+}
+
+// gameJavaVersions are the Java versions the game runs on, starting from a specific game version
+var gameJavaVersions = []struct {
+	gameVersion string
+	javaVersion int
+}{
+	{"26.1", 25},
+	{"1.20.5", 21},
+	{"1.18", 17},
+	{"1.17", 16},
+}
+
+// GetGameJavaVersion returns the Java version a game version normally runs on
+func GetGameJavaVersion(gameVersion string) (int, error) {
+	parsed, err := version.NewVersion(gameVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range gameJavaVersions {
+		if parsed.GreaterThanOrEqual(version.Must(version.NewVersion(entry.gameVersion))) {
+			return entry.javaVersion, nil
+		}
+	}
+
+	return 8, nil
+}
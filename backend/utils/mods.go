@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io"
+	"strings"
+)
+
+// ModConfig represents a single mod declared by a mod JAR
+type ModConfig struct {
+	Id      string
+	Name    string
+	Depends []string
+
+	// The IDs of the mods it can't be used with
+	Breaks []string
+
+	// The IDs of the mods it may not work well with, but can still be used with
+	Conflicts []string
+
+	// If the mod is bundled inside another mod's JAR
+	Bundled bool
+}
+
+// maxBundledDepth limits how deep we look for JARs bundled inside other JARs
+const maxBundledDepth = 3
+
+// bundledJarFolders are the folders Fabric and (Neo)Forge bundle other JARs in
+var bundledJarFolders = []string{"META-INF/jars/", "META-INF/jarjar/"}
+
+// builtInMods are the IDs that are provided by the
+// game or the loader itself, which we can't download
+var builtInMods = map[string]bool{
+	"minecraft":    true,
+	"java":         true,
+	"forge":        true,
+	"neoforge":     true,
+	"fabricloader": true,
+	"quilt_loader": true,
+}
+
+// forgeModsToml represents Forge's mods.toml or NeoForge's neoforge.mods.toml
+type forgeModsToml struct {
+	Mods []struct {
+		ModId       string `toml:"modId"`
+		DisplayName string `toml:"displayName"`
+	} `toml:"mods"`
+	Dependencies map[string][]struct {
+		ModId     string `toml:"modId"`
+		Mandatory *bool  `toml:"mandatory"`
+		Type      string `toml:"type"`
+	} `toml:"dependencies"`
+}
+
+// fabricModJson represents Fabric's fabric.mod.json
+type fabricModJson struct {
+	Id        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Depends   map[string]interface{} `json:"depends"`
+	Breaks    map[string]interface{} `json:"breaks"`
+	Conflicts map[string]interface{} `json:"conflicts"`
+}
+
+// ParseModMetadata attempts to parse the mods declared in a mod JAR
+// using NeoForge's neoforge.mods.toml, Forge's mods.toml or Fabric's fabric.mod.json
+// Mods from JARs bundled inside of it are included as well, marked as bundled
+func ParseModMetadata(filePath string) (mods []ModConfig, err error) {
+	zipReader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return
+	}
+	defer zipReader.Close()
+
+	return parseModArchive(&zipReader.Reader, 0)
+}
+
+// parseModArchive parses the mods declared in an opened
+// mod JAR and in all the JARs that are bundled inside it
+func parseModArchive(reader *zip.Reader, depth int) (mods []ModConfig, err error) {
+	mods, err = parseModArchiveMetadata(reader)
+	if err != nil || depth >= maxBundledDepth {
+		return
+	}
+
+	for _, file := range reader.File {
+		if !isBundledJar(file.Name) {
+			continue
+		}
+
+		data, readErr := readZipEntry(file)
+		if readErr != nil {
+			continue
+		}
+
+		bundledReader, readErr := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if readErr != nil {
+			continue
+		}
+
+		// Plain libraries don't declare any mods, so we can ignore them
+		bundledMods, parseErr := parseModArchive(bundledReader, depth+1)
+		if parseErr != nil {
+			continue
+		}
+
+		// The bundled mods' own dependencies are the concern of the mod bundling them
+		for _, mod := range bundledMods {
+			mod.Bundled = true
+			mod.Depends = nil
+			mod.Breaks = nil
+			mod.Conflicts = nil
+			mods = append(mods, mod)
+		}
+	}
+
+	return
+}
+
+// parseModArchiveMetadata parses the mods declared
+// directly in the metadata of an opened mod JAR
+func parseModArchiveMetadata(reader *zip.Reader) (mods []ModConfig, err error) {
+	files := make(map[string]*zip.File)
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+
+	for _, fileName := range []string{"META-INF/neoforge.mods.toml", "META-INF/mods.toml"} {
+		if file, ok := files[fileName]; ok {
+			var data []byte
+			if data, err = readZipEntry(file); err != nil {
+				return
+			}
+			return parseForgeModsToml(data)
+		}
+	}
+
+	if file, ok := files["fabric.mod.json"]; ok {
+		var data []byte
+		if data, err = readZipEntry(file); err != nil {
+			return
+		}
+		return parseFabricModJson(data)
+	}
+
+	err = fmt.Errorf("no mods.toml or fabric.mod.json found")
+	return
+}
+
+// isBundledJar returns true if the file is a JAR bundled inside a mod JAR
+func isBundledJar(fileName string) bool {
+	if !strings.HasSuffix(fileName, ".jar") {
+		return false
+	}
+
+	for _, folder := range bundledJarFolders {
+		if strings.HasPrefix(fileName, folder) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readZipEntry reads the whole content of a file inside a ZIP archive
+func readZipEntry(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// parseForgeModsToml parses the mods and their required dependencies from a mods.toml
+func parseForgeModsToml(bytes []byte) (mods []ModConfig, err error) {
+	var modsToml forgeModsToml
+	if err = toml.Unmarshal(bytes, &modsToml); err != nil {
+		return
+	}
+
+	for _, mod := range modsToml.Mods {
+		config := ModConfig{
+			Id:   strings.ToLower(mod.ModId),
+			Name: mod.DisplayName,
+		}
+
+		for _, dependency := range modsToml.Dependencies[mod.ModId] {
+
+			// Older Forge versions use the mandatory flag,
+			// NeoForge uses the type, which defaults to required
+			required := dependency.Type == "" || strings.ToLower(dependency.Type) == "required"
+			if dependency.Mandatory != nil {
+				required = *dependency.Mandatory
+			}
+
+			id := strings.ToLower(dependency.ModId)
+			if strings.ToLower(dependency.Type) == "incompatible" {
+				config.Breaks = append(config.Breaks, id)
+				continue
+			}
+
+			if required && !builtInMods[id] {
+				config.Depends = append(config.Depends, id)
+			}
+		}
+
+		mods = append(mods, config)
+	}
+
+	return
+}
+
+// parseFabricModJson parses the mod and its required dependencies from a fabric.mod.json
+func parseFabricModJson(bytes []byte) (mods []ModConfig, err error) {
+	var modJson fabricModJson
+	if err = json.Unmarshal(bytes, &modJson); err != nil {
+		return
+	}
+
+	config := ModConfig{
+		Id:   strings.ToLower(modJson.Id),
+		Name: modJson.Name,
+	}
+
+	for id := range modJson.Depends {
+		id = strings.ToLower(id)
+		if !builtInMods[id] {
+			config.Depends = append(config.Depends, id)
+		}
+	}
+
+	// Fabric has both hard (breaks) and soft (conflicts) incompatibilities
+	for id := range modJson.Breaks {
+		config.Breaks = append(config.Breaks, strings.ToLower(id))
+	}
+
+	for id := range modJson.Conflicts {
+		config.Conflicts = append(config.Conflicts, strings.ToLower(id))
+	}
+
+	mods = append(mods, config)
+	return
+}
@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The ranges that are not covered by the net.IP helpers,
+// but should not be reachable through user-supplied links either
+var blockedNetworks = []string{
+	"0.0.0.0/8",          // "This" network
+	"100.64.0.0/10",      // Carrier-grade NAT
+	"192.0.0.0/24",       // IETF protocol assignments
+	"198.18.0.0/15",      // Benchmarking
+	"240.0.0.0/4",        // Reserved
+	"64:ff9b:1::/48",     // Local-use IPv4/IPv6 translation
+	"2001:db8::/32",      // Documentation
+	"fec0::/10",          // Deprecated site-local
+	"100::/64",           // Discard-only
+	"2002::/16",          // 6to4, which can embed private IPv4 addresses
+	"2001::/32",          // Teredo, which can embed private IPv4 addresses
+	"::/128",             // Unspecified
+	"255.255.255.255/32", // Broadcast
+}
+
+// OutboundPolicy decides which hosts we are allowed to connect to
+// when fetching links that were submitted by users
+type OutboundPolicy struct {
+
+	// Hosts, or IP ranges in the CIDR notation, that are always allowed,
+	// even if they resolve to a private address. Subdomains are allowed as well
+	AllowedHosts []string `yaml:"allowed-hosts"`
+
+	// Hosts, or IP ranges in the CIDR notation, that are never allowed
+	// Subdomains are blocked as well
+	DeniedHosts []string `yaml:"denied-hosts"`
+
+	// Whether private, loopback and link-local addresses may be used,
+	// which should only be enabled if the backend is not exposed
+	AllowPrivate bool `yaml:"allow-private"`
+}
+
+// BlockedError is returned when a link is not allowed by the outbound policy
+type BlockedError struct {
+	Host   string
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%s is not allowed: %s", e.Host, e.Reason)
+}
+
+// IsBlocked returns whether an error was caused by the outbound policy
+func IsBlocked(err error) bool {
+	var blocked *BlockedError
+	return errors.As(err, &blocked)
+}
+
+// Client returns an HTTP client that checks every request, including the redirects,
+// against the policy and only connects to the addresses that are allowed
+func (p *OutboundPolicy) Client() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// A proxy would connect on our behalf, so we would not be able to check the addresses
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := p.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		// Connect to the addresses we have checked, so the
+		// host can not resolve to a different one in the meantime
+		for _, ip := range ips {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+				return conn, nil
+			}
+		}
+
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: &outboundTransport{policy: p, transport: transport},
+	}
+}
+
+// CheckHost returns an error if the host is on the list of denied hosts
+// It does not resolve the host, that is only done once we connect to it
+func (p *OutboundPolicy) CheckHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return &BlockedError{Host: host, Reason: "no host"}
+	}
+
+	if matchesHost(p.DeniedHosts, host, net.ParseIP(host)) {
+		return &BlockedError{Host: host, Reason: "the host is denied"}
+	}
+
+	return nil
+}
+
+// resolve looks up the addresses of a host and returns the ones we are allowed to connect to
+func (p *OutboundPolicy) resolve(ctx context.Context, host string) (ips []net.IP, err error) {
+	if err = p.CheckHost(host); err != nil {
+		return
+	}
+
+	allowed := matchesHost(p.AllowedHosts, strings.ToLower(host), nil)
+
+	var addresses []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		addresses = []net.IPAddr{{IP: ip}}
+	} else if addresses, err = net.DefaultResolver.LookupIPAddr(ctx, host); err != nil {
+		return
+	}
+
+	var reason string
+	for _, address := range addresses {
+		if matchesHost(p.DeniedHosts, "", address.IP) {
+			reason = fmt.Sprintf("%s is denied", address.IP)
+			continue
+		}
+
+		if !allowed && !p.AllowPrivate && !matchesHost(p.AllowedHosts, "", address.IP) && isInternalIP(address.IP) {
+			reason = fmt.Sprintf("%s is an internal address", address.IP)
+			continue
+		}
+
+		ips = append(ips, address.IP)
+	}
+
+	if len(ips) == 0 {
+		if reason == "" {
+			reason = "no addresses found"
+		}
+		err = &BlockedError{Host: host, Reason: reason}
+	}
+
+	return
+}
+
+// outboundTransport checks the host and the scheme of each request before sending it
+type outboundTransport struct {
+	policy    *OutboundPolicy
+	transport http.RoundTripper
+}
+
+func (t *outboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, &BlockedError{Host: req.URL.Host, Reason: fmt.Sprintf("unsupported scheme %s", req.URL.Scheme)}
+	}
+
+	if err := t.policy.CheckHost(req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+
+	return t.transport.RoundTrip(req)
+}
+
+// matchesHost returns whether a host or an address is on a list of hosts and IP ranges
+func matchesHost(list []string, host string, ip net.IP) bool {
+	for _, entry := range list {
+		entry = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(entry)), ".")
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		if entryIp := net.ParseIP(entry); entryIp != nil {
+			if ip != nil && entryIp.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		if host != "" && (host == entry || strings.HasSuffix(host, "."+entry)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isInternalIP returns whether an address is not publicly routable
+func isInternalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+
+	for _, cidr := range blockedNetworks {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
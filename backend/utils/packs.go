@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-version"
+)
+
+// PackMeta represents the formats a data pack or
+// a resource pack declares support for in its pack.mcmeta
+type PackMeta struct {
+	Format    int
+	MinFormat int
+	MaxFormat int
+}
+
+// Supports returns true if the pack declares support for a specific format
+func (pm PackMeta) Supports(format int) bool {
+	if pm.MinFormat != 0 || pm.MaxFormat != 0 {
+		return format >= pm.MinFormat && format <= pm.MaxFormat
+	}
+	return pm.Format == format
+}
+
+// String returns the formats the pack supports in a readable way
+func (pm PackMeta) String() string {
+	if (pm.MinFormat != 0 || pm.MaxFormat != 0) && pm.MinFormat != pm.MaxFormat {
+		return fmt.Sprintf("formats %d-%d", pm.MinFormat, pm.MaxFormat)
+	}
+
+	if pm.MinFormat != 0 {
+		return fmt.Sprintf("format %d", pm.MinFormat)
+	}
+
+	return fmt.Sprintf("format %d", pm.Format)
+}
+
+// packMcmeta represents the pack.mcmeta of a data pack or a resource pack
+type packMcmeta struct {
+	Pack *struct {
+		PackFormat       int             `json:"pack_format"`
+		SupportedFormats json.RawMessage `json:"supported_formats"`
+		MinFormat        json.RawMessage `json:"min_format"`
+		MaxFormat        json.RawMessage `json:"max_format"`
+	} `json:"pack"`
+}
+
+// ParsePackMcmeta attempts to parse the pack.mcmeta of a data pack or a resource pack
+func ParsePackMcmeta(filePath string) (meta PackMeta, err error) {
+	bytes, err := ReadJarFile(filePath, "pack.mcmeta")
+	if err != nil {
+		return
+	}
+
+	var mcmeta packMcmeta
+	if err = json.Unmarshal(bytes, &mcmeta); err != nil {
+		return
+	}
+
+	if mcmeta.Pack == nil {
+		err = fmt.Errorf("no pack section found")
+		return
+	}
+
+	meta.Format = mcmeta.Pack.PackFormat
+
+	// Newer versions use a minimum and a maximum, older ones a range of supported formats
+	if len(mcmeta.Pack.MinFormat) > 0 || len(mcmeta.Pack.MaxFormat) > 0 {
+		meta.MinFormat, _ = parsePackFormat(mcmeta.Pack.MinFormat)
+		meta.MaxFormat, _ = parsePackFormat(mcmeta.Pack.MaxFormat)
+	} else if len(mcmeta.Pack.SupportedFormats) > 0 {
+		meta.MinFormat, meta.MaxFormat = parsePackFormatRange(mcmeta.Pack.SupportedFormats)
+	}
+
+	if meta.Format == 0 && meta.MinFormat == 0 && meta.MaxFormat == 0 {
+		err = fmt.Errorf("no pack format found")
+	}
+
+	return
+}
+
+// parsePackFormat parses a single format, which is either
+// a number or a list of the major and the minor version
+func parsePackFormat(data json.RawMessage) (format int, ok bool) {
+	if err := json.Unmarshal(data, &format); err == nil {
+		return format, true
+	}
+
+	var parts []int
+	if err := json.Unmarshal(data, &parts); err == nil && len(parts) > 0 {
+		return parts[0], true
+	}
+
+	return 0, false
+}
+
+// parsePackFormatRange parses the supported formats, which are either
+// a number, a list of two numbers or an object with the bounds
+func parsePackFormatRange(data json.RawMessage) (min, max int) {
+	if format, ok := parsePackFormat(data); ok {
+		var parts []int
+		if err := json.Unmarshal(data, &parts); err == nil && len(parts) == 2 {
+			return parts[0], parts[1]
+		}
+		return format, format
+	}
+
+	var bounds struct {
+		MinInclusive int `json:"min_inclusive"`
+		MaxInclusive int `json:"max_inclusive"`
+	}
+	if err := json.Unmarshal(data, &bounds); err == nil {
+		return bounds.MinInclusive, bounds.MaxInclusive
+	}
+
+	return 0, 0
+}
+
+// packFormat represents the format packs use, starting from a specific game version
+type packFormat struct {
+	gameVersion string
+	format      int
+}
+
+// datapackFormats are the data pack formats of each game version, from the newest
+// The formats of the versions newer than the ones we know of are left as 0
+var datapackFormats = []packFormat{
+	{"1.21.9", 0},
+	{"1.21.7", 81},
+	{"1.21.6", 80},
+	{"1.21.5", 71},
+	{"1.21.4", 61},
+	{"1.21.2", 57},
+	{"1.21", 48},
+	{"1.20.5", 41},
+	{"1.20.3", 26},
+	{"1.20.2", 18},
+	{"1.20", 15},
+	{"1.19.4", 12},
+	{"1.19", 10},
+	{"1.18.2", 9},
+	{"1.18", 8},
+	{"1.17", 7},
+	{"1.16.2", 6},
+	{"1.15", 5},
+	{"1.13", 4},
+}
+
+// resourcePackFormats are the resource pack formats of each game version, from the newest
+// The formats of the versions newer than the ones we know of are left as 0
+var resourcePackFormats = []packFormat{
+	{"1.21.9", 0},
+	{"1.21.7", 64},
+	{"1.21.6", 63},
+	{"1.21.5", 55},
+	{"1.21.4", 46},
+	{"1.21.2", 42},
+	{"1.21", 34},
+	{"1.20.5", 32},
+	{"1.20.3", 22},
+	{"1.20.2", 18},
+	{"1.20", 15},
+	{"1.19.4", 13},
+	{"1.19.3", 12},
+	{"1.19", 9},
+	{"1.18", 8},
+	{"1.17", 7},
+	{"1.16.2", 6},
+	{"1.15", 5},
+	{"1.13", 4},
+}
+
+// getPackFormat finds the format of a game version, returns false
+// for the versions that we do not know the format of
+func getPackFormat(formats []packFormat, gameVersion string) (int, bool) {
+	parsed, err := version.NewVersion(gameVersion)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, entry := range formats {
+		if parsed.GreaterThanOrEqual(version.Must(version.NewVersion(entry.gameVersion))) {
+			return entry.format, entry.format != 0
+		}
+	}
+
+	return 0, false
+}
+
+// GetDatapackFormat returns the data pack format of a game version
+func GetDatapackFormat(gameVersion string) (int, bool) {
+	return getPackFormat(datapackFormats, gameVersion)
+}
+
+// GetResourcePackFormat returns the resource pack format of a game version
+func GetResourcePackFormat(gameVersion string) (int, bool) {
+	return getPackFormat(resourcePackFormats, gameVersion)
+}
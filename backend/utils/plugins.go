@@ -2,19 +2,83 @@ package utils
 
 import (
 	"archive/zip"
+	"encoding/json"
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"io"
+	"strings"
 )
 
 type PluginConfig struct {
-	Name    string   `yaml:"name"`
-	Depends []string `yaml:"depend"`
+	Name           string   `yaml:"name"`
+	Depends        []string `yaml:"depend"`
+	SoftDepends    []string `yaml:"softdepend"`
+	LoadBefore     []string `yaml:"loadbefore"`
+	Libraries      []string `yaml:"libraries"`
+	ApiVersion     string   `yaml:"api-version"`
+	FoliaSupported bool     `yaml:"folia-supported"`
 }
 
-// ParsePluginYaml attempts to parse a plugin JAR's plugin.yml
-// as a YAML document
-func ParsePluginYaml(filePath string) (pluginYaml PluginConfig, err error) {
+// paperDependency represents a single dependency in Paper's paper-plugin.yml
+type paperDependency struct {
+	Load     string `yaml:"load"`
+	Required *bool  `yaml:"required"`
+}
+
+// paperPluginConfig represents a Paper plugin's paper-plugin.yml,
+// which declares its dependencies separately for the bootstrap and the server
+type paperPluginConfig struct {
+	Name           string `yaml:"name"`
+	ApiVersion     string `yaml:"api-version"`
+	FoliaSupported bool   `yaml:"folia-supported"`
+	Dependencies   struct {
+		Bootstrap map[string]paperDependency `yaml:"bootstrap"`
+		Server    map[string]paperDependency `yaml:"server"`
+	} `yaml:"dependencies"`
+}
+
+// bungeeConfig represents a BungeeCord plugin's bungee.yml,
+// which uses slightly different keys than Bukkit's plugin.yml
+type bungeeConfig struct {
+	Name        string   `yaml:"name"`
+	Depends     []string `yaml:"depends"`
+	SoftDepends []string `yaml:"softDepends"`
+}
+
+// velocityConfig represents a Velocity plugin's velocity-plugin.json
+type velocityConfig struct {
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	Dependencies []struct {
+		Id       string `json:"id"`
+		Optional bool   `json:"optional"`
+	} `json:"dependencies"`
+}
+
+// spongePluginsConfig represents a Sponge plugin's META-INF/sponge_plugins.json
+type spongePluginsConfig struct {
+	Plugins []struct {
+		Id           string `json:"id"`
+		Name         string `json:"name"`
+		Dependencies []struct {
+			Id       string `json:"id"`
+			Optional bool   `json:"optional"`
+		} `json:"dependencies"`
+	} `json:"plugins"`
+}
+
+// builtInSpongePlugins are the plugin IDs that are provided by the Sponge server itself
+var builtInSpongePlugins = map[string]bool{
+	"minecraft":     true,
+	"sponge":        true,
+	"spongeapi":     true,
+	"spongeforge":   true,
+	"spongevanilla": true,
+}
+
+// ReadJarFile attempts to read the contents of a
+// single file from a JAR by its exact name
+func ReadJarFile(filePath, fileName string) (data []byte, err error) {
 
 	// Open the ZIP archive
 	zipReader, err := zip.OpenReader(filePath)
@@ -24,7 +88,7 @@ func ParsePluginYaml(filePath string) (pluginYaml PluginConfig, err error) {
 	defer zipReader.Close()
 
 	for _, f := range zipReader.File {
-		if f.Name == "plugin.yml" {
+		if f.Name == fileName {
 
 			// Open the file from the JAR
 			var rc io.ReadCloser
@@ -35,21 +99,164 @@ func ParsePluginYaml(filePath string) (pluginYaml PluginConfig, err error) {
 			defer rc.Close()
 
 			// Read all the data
-			var bytes []byte
-			bytes, err = io.ReadAll(rc)
-			if err != nil {
-				return
+			return io.ReadAll(rc)
+		}
+	}
+
+	err = fmt.Errorf("no %s found", fileName)
+	return
+}
+
+// ParsePluginYaml attempts to parse a plugin JAR's plugin.yml
+// as a YAML document
+func ParsePluginYaml(filePath string) (pluginYaml PluginConfig, err error) {
+	bytes, err := ReadJarFile(filePath, "plugin.yml")
+	if err != nil {
+		return
+	}
+
+	// Read the data as a YAML document into our struct
+	err = yaml.Unmarshal(bytes, &pluginYaml)
+	return
+}
+
+// ParsePaperPluginYaml attempts to parse a Paper plugin JAR's paper-plugin.yml
+// as a YAML document. Just like Paper, if there is no paper-plugin.yml,
+// it will fall back to the plugin.yml
+func ParsePaperPluginYaml(filePath string) (pluginYaml PluginConfig, err error) {
+	bytes, err := ReadJarFile(filePath, "paper-plugin.yml")
+	if err != nil {
+		return ParsePluginYaml(filePath)
+	}
+
+	var paperYaml paperPluginConfig
+	if err = yaml.Unmarshal(bytes, &paperYaml); err != nil {
+		return
+	}
+
+	pluginYaml = PluginConfig{
+		Name:           paperYaml.Name,
+		ApiVersion:     paperYaml.ApiVersion,
+		FoliaSupported: paperYaml.FoliaSupported,
+	}
+
+	// Dependencies are required by default, and the ones that are loaded
+	// after the plugin are the same as the loadbefore of a plugin.yml
+	added := make(map[string]bool)
+	for _, dependencies := range []map[string]paperDependency{paperYaml.Dependencies.Bootstrap, paperYaml.Dependencies.Server} {
+		for name, dependency := range dependencies {
+			if strings.ToUpper(dependency.Load) == "AFTER" {
+				pluginYaml.LoadBefore = append(pluginYaml.LoadBefore, name)
 			}
 
-			// Read the data as a YAML document into our struct
-			if err = yaml.Unmarshal(bytes, &pluginYaml); err != nil {
-				return
+			if added[name] {
+				continue
 			}
+			added[name] = true
 
+			if dependency.Required == nil || *dependency.Required {
+				pluginYaml.Depends = append(pluginYaml.Depends, name)
+			} else {
+				pluginYaml.SoftDepends = append(pluginYaml.SoftDepends, name)
+			}
+		}
+	}
+
+	return
+}
+
+// ParseBungeeYaml attempts to parse a BungeeCord plugin JAR's bungee.yml
+// as a YAML document. Just like BungeeCord, if there is no bungee.yml,
+// it will fall back to the plugin.yml
+func ParseBungeeYaml(filePath string) (pluginYaml PluginConfig, err error) {
+	bytes, err := ReadJarFile(filePath, "bungee.yml")
+	if err != nil {
+		if bytes, err = ReadJarFile(filePath, "plugin.yml"); err != nil {
+			err = fmt.Errorf("no bungee.yml or plugin.yml found")
 			return
 		}
 	}
 
-	err = fmt.Errorf("no plugin.yml found")
+	var bungeeYaml bungeeConfig
+	if err = yaml.Unmarshal(bytes, &bungeeYaml); err != nil {
+		return
+	}
+
+	pluginYaml = PluginConfig{
+		Name:        bungeeYaml.Name,
+		Depends:     bungeeYaml.Depends,
+		SoftDepends: bungeeYaml.SoftDepends,
+	}
+
+	return
+}
+
+// ParseVelocityPluginJson attempts to parse a Velocity plugin JAR's
+// velocity-plugin.json. Velocity refers to dependencies by their ID,
+// so we will use that as the name of the plugin
+func ParseVelocityPluginJson(filePath string) (pluginYaml PluginConfig, err error) {
+	bytes, err := ReadJarFile(filePath, "velocity-plugin.json")
+	if err != nil {
+		return
+	}
+
+	var velocityJson velocityConfig
+	if err = json.Unmarshal(bytes, &velocityJson); err != nil {
+		return
+	}
+
+	pluginYaml.Name = strings.ToLower(velocityJson.Id)
+	for _, dependency := range velocityJson.Dependencies {
+		if dependency.Optional {
+			pluginYaml.SoftDepends = append(pluginYaml.SoftDepends, dependency.Id)
+		} else {
+			pluginYaml.Depends = append(pluginYaml.Depends, dependency.Id)
+		}
+	}
+
+	return
+}
+
+// ParseSpongePluginsJson attempts to parse a Sponge plugin JAR's
+// META-INF/sponge_plugins.json. A single JAR can contain several plugins,
+// so we will use the first one's ID as the name and combine their dependencies
+func ParseSpongePluginsJson(filePath string) (pluginYaml PluginConfig, err error) {
+	bytes, err := ReadJarFile(filePath, "META-INF/sponge_plugins.json")
+	if err != nil {
+		return
+	}
+
+	var spongeJson spongePluginsConfig
+	if err = json.Unmarshal(bytes, &spongeJson); err != nil {
+		return
+	}
+
+	if len(spongeJson.Plugins) == 0 {
+		err = fmt.Errorf("no plugins declared")
+		return
+	}
+
+	// Plugins can depend on the others from the same JAR
+	provided := make(map[string]bool)
+	for _, plugin := range spongeJson.Plugins {
+		provided[strings.ToLower(plugin.Id)] = true
+	}
+
+	pluginYaml.Name = strings.ToLower(spongeJson.Plugins[0].Id)
+	for _, plugin := range spongeJson.Plugins {
+		for _, dependency := range plugin.Dependencies {
+			id := strings.ToLower(dependency.Id)
+			if provided[id] || builtInSpongePlugins[id] {
+				continue
+			}
+
+			if dependency.Optional {
+				pluginYaml.SoftDepends = append(pluginYaml.SoftDepends, id)
+			} else {
+				pluginYaml.Depends = append(pluginYaml.Depends, id)
+			}
+		}
+	}
+
 	return
 }
@@ -2,12 +2,21 @@ package utils
 
 import (
 	"archive/zip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"unicode"
 )
 
 type Simple struct {
@@ -59,12 +68,117 @@ func GetRegexGroups(regex *regexp.Regexp, data string) (paramsMap map[string]str
 	return paramsMap
 }
 
+// VerifyChecksum hashes a file with the given algorithm and compares
+// it against the expected hex encoded hash
+// If no hash is provided, there is nothing to compare, so it will pass
+func VerifyChecksum(filePath, algorithm, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	hashes, err := HashFile(filePath, algorithm)
+	if err != nil {
+		return err
+	}
+
+	if actual := hashes[algorithm]; !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch, expected %s but got %s", expected, actual)
+	}
+
+	return nil
+}
+
+// HashFile hashes a file with each of the given algorithms in a single
+// pass and returns the hex encoded hashes keyed by the algorithm
+func HashFile(filePath string, algorithms ...string) (hashes map[string]string, err error) {
+	hashers := make(map[string]hash.Hash)
+	writers := make([]io.Writer, 0)
+	for _, algorithm := range algorithms {
+		var hasher hash.Hash
+		switch strings.ToLower(algorithm) {
+		case "md5":
+			hasher = md5.New()
+		case "sha1":
+			hasher = sha1.New()
+		case "sha256":
+			hasher = sha256.New()
+		case "sha512":
+			hasher = sha512.New()
+		default:
+			err = fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+			return
+		}
+
+		hashers[algorithm] = hasher
+		writers = append(writers, hasher)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(io.MultiWriter(writers...), file); err != nil {
+		return
+	}
+
+	hashes = make(map[string]string)
+	for algorithm, hasher := range hashers {
+		hashes[algorithm] = hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	return
+}
+
+// SafeJoin joins a relative path onto a folder, ensuring
+// that the result does not escape the folder
+func SafeJoin(folderPath, relativePath string) (string, error) {
+	fullPath := filepath.Join(folderPath, filepath.FromSlash(relativePath))
+	rel, err := filepath.Rel(folderPath, fullPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path: %s", relativePath)
+	}
+
+	return fullPath, nil
+}
+
+// The longest file name SanitizeFileName returns, without the extension
+const maxFileNameLength = 100
+
+// SanitizeFileName turns a name into one that can be safely used as the name
+// of a file in a folder, by replacing anything that could be used to escape
+// the folder or that is not allowed by some file systems
+func SanitizeFileName(name string) string {
+	var builder strings.Builder
+	for _, char := range name {
+		switch {
+		case unicode.IsLetter(char), unicode.IsDigit(char), strings.ContainsRune(" .-_+()[]", char):
+			builder.WriteRune(char)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+
+	// Hidden files, relative paths and trailing dots are not allowed either
+	sanitized := strings.Trim(builder.String(), " .")
+	extension := filepath.Ext(sanitized)
+	base := strings.TrimSuffix(sanitized, extension)
+	if runes := []rune(base); len(runes) > maxFileNameLength {
+		base = string(runes[:maxFileNameLength])
+	}
+
+	return base + extension
+}
+
 type ZipInfo struct {
 	Path string
 	Size int64
 }
 
-func ZipFolder(zipPath, folderPath string) (info ZipInfo, err error) {
+// ZipFolder creates a ZIP archive with all the contents of a folder,
+// except for the files the skip function returns true for, if it's passed
+func ZipFolder(zipPath, folderPath string, skip func(path string) bool) (info ZipInfo, err error) {
 	// Create a new zip file
 	zipFile, err := os.Create(zipPath)
 	if err != nil {
@@ -101,6 +215,10 @@ func ZipFolder(zipPath, folderPath string) (info ZipInfo, err error) {
 			return nil
 		}
 
+		if skip != nil && !info.IsDir() && skip(path) {
+			return nil
+		}
+
 		header, err := zip.FileInfoHeader(info)
 		if err != nil {
 			return err
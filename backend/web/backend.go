@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"geri.dev/pack-builder/checker"
@@ -17,7 +18,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 )
 
 type Backend struct {
@@ -36,8 +36,21 @@ func NewBackend(cfg *config.Config) (backend Backend) {
 		c: checker.Checker{
 			Spigot:         providers.NewSpigotProvider(cfg),
 			Modrinth:       providers.NewModrinthProvider(cfg),
+			Hangar:         providers.NewHangarProvider(cfg),
+			Maven:          providers.NewMavenProvider(cfg),
+			Ore:            providers.NewOreProvider(cfg),
+			CurseForge:     providers.NewCurseForgeProvider(cfg),
 			GitHub:         providers.NewGitHubProvider(cfg),
 			DirectDownload: providers.NewDirectDownloadProvider(cfg),
+			PaperMC:        providers.NewPaperMCProvider(cfg),
+			Purpur:         providers.NewPurpurProvider(cfg),
+			Compatibility:  cfg.Compatibility,
+			Pool:           checker.NewDownloadPool(cfg.Downloads.Workers, cfg.Downloads.SessionWorkers),
+			Quotas:         cfg.Quotas,
+			Client:         cfg.Outbound.Client(),
+
+			PreliminaryWorkers: cfg.Preliminary.Workers,
+			DownloadRetries:    cfg.Downloads.Retries,
 		},
 
 		downloads: make(map[uuid.UUID]*checker.Package),
@@ -58,11 +71,18 @@ func NewBackend(cfg *config.Config) (backend Backend) {
 	backend.c.PluginProviders = []providers.PluginProvider{
 		&backend.c.Spigot,
 		&backend.c.Modrinth,
+		&backend.c.Hangar,
+		&backend.c.Ore,
+		&backend.c.CurseForge,
 	}
 	backend.c.ExternalProviders = []providers.ExternalProvider{
 		&backend.c.GitHub,
 		&backend.c.DirectDownload,
 	}
+	backend.c.ServerProviders = []providers.ServerProvider{
+		&backend.c.PaperMC,
+		&backend.c.Purpur,
+	}
 
 	return
 }
@@ -78,6 +98,10 @@ func (b *Backend) LoadSessions() (err error) {
 		return
 	}
 
+	for _, session := range b.sessions {
+		session.Restore()
+	}
+
 	return
 }
 
@@ -146,6 +170,11 @@ func (b *Backend) CreationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := b.c.CheckLinkCount(len(request.Links)); err != nil {
+		utils.SendJSON(w, 400, utils.Simple{Message: err.Error()})
+		return
+	}
+
 	issues := make([]utils.Tracker, 0)
 	for trackerId, link := range request.Links {
 		// Verify the ID is a valid UUID
@@ -193,6 +222,54 @@ func (b *Backend) CreationHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ImportHandler handles creating a new session from an existing modpack,
+// either uploaded as an archive or linked to as a packwiz pack.toml
+func (b *Backend) ImportHandler(w http.ResponseWriter, r *http.Request) {
+
+	// The pack counts as a single file, so it can't be larger than one
+	maxSize := b.cfg.Quotas.GetMaxFileSize()
+	if maxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		utils.SendJSON(w, 400, utils.Simple{Message: fmt.Sprintf("unable to read request: %s", err)})
+		return
+	}
+
+	var source checker.PackSource
+	var err error
+	if link := r.FormValue("url"); link != "" {
+		source, err = checker.NewRemotePackSource(link, b.c.Client, maxSize, b.cfg.Quotas.GetRequestTimeout())
+	} else {
+		file, header, fileErr := r.FormFile("pack")
+		if fileErr != nil {
+			utils.SendJSON(w, 400, utils.Simple{Message: "no pack provided"})
+			return
+		}
+		defer file.Close()
+
+		source, err = checker.NewZipPackSource(file, header.Size, maxSize)
+	}
+
+	if err != nil {
+		utils.SendJSON(w, 400, utils.Simple{Message: fmt.Sprintf("unable to read pack: %s", err)})
+		return
+	}
+
+	session, result, err := b.c.ImportPack(source)
+	if err != nil {
+		utils.SendJSON(w, 400, utils.Simple{Message: fmt.Sprintf("unable to import pack: %s", err)})
+		return
+	}
+
+	b.sessions[session.Id] = session
+	utils.SendJSON(w, 200, map[string]interface{}{
+		"id":     session.Id.String(),
+		"import": result,
+	})
+}
+
 // IndexHandler returns information about a specific session
 func (b *Backend) IndexHandler(w http.ResponseWriter, r *http.Request) {
 	session := b.getSession(w, r)
@@ -260,10 +337,7 @@ func (b *Backend) SocketHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Keep track of the socket with the session
-	session.Sockets = append(session.Sockets, checker.SocketTracker{
-		Socket: ws,
-		Lock:   &sync.Mutex{},
-	})
+	session.AddSocket(ws)
 
 	// Ensure the socket is cleaned up
 	defer func() {
@@ -297,9 +371,12 @@ func (b *Backend) SocketHandler(w http.ResponseWriter, r *http.Request) {
 
 		switch sockets.Message(command) {
 		case sockets.Preliminary:
-			_ = session.BroadcastToSockets(sockets.PreliminaryStart, nil)
-			b.c.PreliminaryChecks(session)
-			_ = session.BroadcastToSockets(sockets.PreliminaryDone, session)
+			err := b.runStage(session, sockets.PreliminaryStart, sockets.PreliminaryDone, func(ctx context.Context) {
+				b.c.PreliminaryChecks(ctx, session)
+			})
+			if err != nil {
+				_ = session.BroadcastToSockets(sockets.StageRunning, utils.Simple{Message: err.Error()})
+			}
 			break
 
 		case sockets.ToggleLink:
@@ -331,15 +408,68 @@ func (b *Backend) SocketHandler(w http.ResponseWriter, r *http.Request) {
 			break
 
 		case sockets.Process:
-			_ = session.BroadcastToSockets(sockets.ProcessStart, nil)
-			b.c.DownloadFiles(session)
-			b.c.PostProcessing(session)
-			_ = session.BroadcastToSockets(sockets.ProcessDone, session)
+			if !session.OverallState.Preliminary {
+				continue
+			}
+
+			err := b.runStage(session, sockets.ProcessStart, sockets.ProcessDone, func(ctx context.Context) {
+				b.runProcess(ctx, session)
+			})
+			if err != nil {
+				_ = session.BroadcastToSockets(sockets.StageRunning, utils.Simple{Message: err.Error()})
+			}
+			break
+
+		case sockets.AcceptSuggestion:
+			if !session.OverallState.PostProcessing {
+				continue
+			}
+
+			var data struct {
+				Id   string
+				Name string
+			}
+
+			if err := json.Unmarshal(rawData, &data); err != nil {
+				continue
+			}
+
+			id, err := uuid.Parse(data.Id)
+			if err != nil {
+				continue
+			}
+
+			err = b.runStage(session, "", "", func(ctx context.Context) {
+				if err := b.c.AcceptSuggestion(ctx, session, id, data.Name); err != nil {
+					_ = session.BroadcastToSockets(sockets.SuggestionError, utils.Simple{Message: err.Error()})
+					return
+				}
+
+				_ = session.BroadcastToSockets(sockets.SuggestionDone, session.Links[id])
+			})
+			if err != nil {
+				_ = session.BroadcastToSockets(sockets.StageRunning, utils.Simple{Message: err.Error()})
+			}
+			break
+
+		case sockets.Cancel:
+			session.CancelStage()
 			break
 
 		case sockets.Package:
+			if !session.OverallState.PostProcessing {
+				continue
+			}
+
+			// Packaging is quick, but it should not run while files are still being downloaded
+			if _, err := session.StartStage(); err != nil {
+				_ = session.BroadcastToSockets(sockets.StageRunning, utils.Simple{Message: err.Error()})
+				continue
+			}
+
 			_ = session.BroadcastToSockets(sockets.PackageStart, nil)
 			b.c.Package(session)
+			session.FinishStage()
 			_ = session.BroadcastToSockets(sockets.PackageDone, session)
 			break
 
@@ -385,6 +515,50 @@ func (b *Backend) SocketHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// runStage runs a stage of a session on a new thread, so the socket can still receive
+// commands while it is running, such as cancelling it. The start and done messages
+// are broadcast before and after the stage, unless they are empty or it was cancelled
+func (b *Backend) runStage(session *checker.Session, start, done sockets.Message, stage func(ctx context.Context)) error {
+	ctx, err := session.StartStage()
+	if err != nil {
+		return err
+	}
+
+	if start != "" {
+		_ = session.BroadcastToSockets(start, nil)
+	}
+
+	go func() {
+		stage(ctx)
+
+		// Finishing the stage cancels its context, so check it before
+		cancelled := ctx.Err() != nil
+		session.FinishStage()
+
+		if cancelled {
+			_ = session.BroadcastToSockets(sockets.Cancelled, session)
+		} else if done != "" {
+			_ = session.BroadcastToSockets(done, session)
+		}
+
+		// Todo (notgeri):
+		go b.SaveSessions()
+	}()
+
+	return nil
+}
+
+// runProcess downloads the files of a session and post-processes them,
+// unless the downloads were cancelled
+func (b *Backend) runProcess(ctx context.Context, session *checker.Session) {
+	b.c.DownloadFiles(ctx, session)
+	if ctx.Err() != nil {
+		return
+	}
+
+	b.c.PostProcessing(ctx, session)
+}
+
 // PreliminaryHandler starts the preliminary checks for a session
 func (b *Backend) PreliminaryHandler(w http.ResponseWriter, r *http.Request) {
 	session := b.getSession(w, r)
@@ -399,7 +573,14 @@ func (b *Backend) PreliminaryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Run the checks on a new thread
-	go b.c.PreliminaryChecks(session)
+	err := b.runStage(session, sockets.PreliminaryStart, sockets.PreliminaryDone, func(ctx context.Context) {
+		b.c.PreliminaryChecks(ctx, session)
+	})
+	if err != nil {
+		utils.SendJSON(w, 409, utils.Simple{Message: err.Error()})
+		return
+	}
+
 	utils.SendJSON(w, 201, nil)
 }
 
@@ -417,10 +598,30 @@ func (b *Backend) ProcessHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Run the downloads and post-processing on a new thread
-	go func() {
-		b.c.DownloadFiles(session)
-		b.c.PostProcessing(session)
-	}()
+	err := b.runStage(session, sockets.ProcessStart, sockets.ProcessDone, func(ctx context.Context) {
+		b.runProcess(ctx, session)
+	})
+	if err != nil {
+		utils.SendJSON(w, 409, utils.Simple{Message: err.Error()})
+		return
+	}
+
+	utils.SendJSON(w, 201, nil)
+}
+
+// CancelHandler cancels the stage that is currently running for a session
+// and waits for it to stop, so the session can be used again right away
+func (b *Backend) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	session := b.getSession(w, r)
+	if session == nil {
+		return
+	}
+
+	if !session.CancelStage() {
+		utils.SendJSON(w, 400, utils.Simple{Message: "no stage is running for the session"})
+		return
+	}
+
 	utils.SendJSON(w, 201, nil)
 }
 
@@ -11,22 +11,44 @@ const (
 	Package     Message = "package"
 	GetDownload Message = "get_download"
 	Delete      Message = "delete"
+	Cancel      Message = "cancel"
+
+	AcceptSuggestion Message = "accept_suggestion"
 
 	// Messages sent to the client
-	Connected        Message = "connected"
-	PreliminaryStart Message = "preliminary_start"
-	PreliminaryStep  Message = "preliminary_step"
-	PreliminaryDone  Message = "preliminary_done"
-	ProcessStart     Message = "process_start"
-	ProcessStep      Message = "process_step"
-	ProcessDone      Message = "process_done"
-	PackageStart     Message = "package_start"
-	PackageDone      Message = "package_done"
-	GetDownloadStart Message = "get_download_start"
-	GetDownloadDone  Message = "get_download_done"
-	GetDownloadError Message = "get_download_error"
-	Deleted          Message = "deleted"
+	Connected           Message = "connected"
+	PreliminaryStart    Message = "preliminary_start"
+	PreliminaryStep     Message = "preliminary_step"
+	PreliminaryProgress Message = "preliminary_progress"
+	PreliminaryDone     Message = "preliminary_done"
+	ProcessStart        Message = "process_start"
+	ProcessStep         Message = "process_step"
+	ProcessDone         Message = "process_done"
+	ServerStep          Message = "server_step"
+	DownloadProgress    Message = "download_progress"
+	SessionProgress     Message = "session_progress"
+	PackageStart        Message = "package_start"
+	PackageDone         Message = "package_done"
+	GetDownloadStart    Message = "get_download_start"
+	GetDownloadDone     Message = "get_download_done"
+	GetDownloadError    Message = "get_download_error"
+	Deleted             Message = "deleted"
+	SuggestionDone      Message = "suggestion_done"
+	SuggestionError     Message = "suggestion_error"
+	Cancelled           Message = "cancelled"
+	StageRunning        Message = "stage_running"
 
 	// Error types
 	NoSuitableVersion ErrorType = "no_suitable_version"
+	NotFoliaSupported ErrorType = "not_folia_supported"
+	DuplicateBundled  ErrorType = "duplicate_bundled"
+	DuplicateName     ErrorType = "duplicate_name"
+	Incompatible      ErrorType = "incompatible"
+	SoftIncompatible  ErrorType = "soft_incompatible"
+	NewerJava         ErrorType = "newer_java"
+	PackFormat        ErrorType = "pack_format"
+	DependencyCycle   ErrorType = "dependency_cycle"
+	NewerApiVersion   ErrorType = "newer_api_version"
+	BlockedLink       ErrorType = "blocked_link"
+	QuotaExceeded     ErrorType = "quota_exceeded"
 )